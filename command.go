@@ -0,0 +1,336 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"exec"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"utf8"
+)
+
+// A Command is a subcommand of the program, such as "build" in
+//
+//	tool [OPTION]... build [OPTION]... PACKAGE...
+//
+// with flags and arguments of its own.  Commands may have commands of their
+// own in turn.
+type Command struct {
+	Name     string              // name on the command line
+	Summary  string              // one-line description, for lists of commands
+	Synopsis string              // what follows the options in the usage message
+	Flags    func()              // defines the flags of the command (optional)
+	Run      func(args []string) // runs the command with its non-flag arguments
+
+	commands []*Command
+}
+
+// root holds the commands of the program.
+var root = new(Command)
+
+// Plugins enables external commands: if the command named on the command line
+// is not one that has been added, Dispatch runs the program PROG-NAME from
+// $PATH in its place, where PROG is the name of the program (and of the
+// commands before NAME, joined by '-').  The program gets the rest of the
+// arguments, and the values of the persistent flags in environment variables
+// named like PROG_FLAG_NAME.  The program needs no commands of its own to
+// dispatch to external ones.
+var Plugins = false
+
+// AddCommand adds c to the commands of the program.
+func AddCommand(c *Command) { root.AddCommand(c) }
+
+// AddCommand adds sub to the commands of c.
+func (c *Command) AddCommand(sub *Command) {
+	if c.lookup(sub.Name) != nil || sub.Name == "help" {
+		fmt.Fprintln(os.Stderr, "command redefined:", sub.Name)
+		panic("command redefinition")
+	}
+	c.commands = append(c.commands, sub)
+}
+
+// lookup returns the command of c with the given name, or nil.
+func (c *Command) lookup(name string) *Command {
+	for _, sub := range c.commands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// dispatches reports whether the non-flag arguments of c start with the name
+// of a command to dispatch to.
+func (c *Command) dispatches() bool {
+	return len(c.commands) > 0 || Plugins && c == root
+}
+
+// commandPath returns the names of the program and of the command the flags
+// belong to, e.g. "tool build".
+func commandPath() string {
+	if flags.path == "" {
+		return progName()
+	}
+	return flags.path
+}
+
+// printCommands prints to Output the list of commands of c, if it has any.
+func (c *Command) printCommands() {
+	if !c.dispatches() {
+		return
+	}
+	if len(c.commands) > 0 {
+		fmt.Fprintf(Output, "\n%s\n", Gettext("Commands:"))
+		for _, sub := range c.commands {
+			printCommand(sub.Name, Gettext(sub.Summary))
+		}
+	}
+	if Plugins {
+		var names []string
+		for _, name := range plugins(commandPath()) {
+			if c.lookup(name) == nil {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			fmt.Fprintf(Output, "\n%s\n", Gettext("External commands:"))
+			for _, name := range names {
+				printCommand(name, "")
+			}
+		}
+	}
+	fmt.Fprintf(Output, "\n"+Gettext("Try '%s help COMMAND' for more information on a command.\n"), commandPath())
+}
+
+// printCommand prints to Output a line of a list of commands.
+func printCommand(name, summary string) {
+	name = "  " + name
+	if summary == "" {
+		fmt.Fprintln(Output, name)
+		return
+	}
+	if n := utf8.RuneCountInString(name); n > helpColumn-2 {
+		fmt.Fprintf(Output, "%s\n%s", name, strings.Repeat(" ", helpColumn))
+	} else {
+		fmt.Fprint(Output, name+strings.Repeat(" ", helpColumn-n))
+	}
+	fmt.Fprintln(Output, summary)
+}
+
+// plugins returns the names of the external commands found in $PATH for the
+// program and commands named by path, e.g. "tool build".
+func plugins(path string) []string {
+	prefix := strings.Replace(path, " ", "-", -1) + "-"
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range strings.Split(os.Getenv("PATH"), ":", -1) {
+		if dir == "" {
+			dir = "."
+		}
+		d, err := os.Open(dir, os.O_RDONLY, 0)
+		if err != nil {
+			continue
+		}
+		files, _ := d.Readdirnames(-1)
+		d.Close()
+		for _, file := range files {
+			if !strings.HasPrefix(file, prefix) || len(file) == len(prefix) {
+				continue
+			}
+			name := file[len(prefix):]
+			if seen[name] {
+				continue
+			}
+			fi, err := os.Stat(dir + "/" + file)
+			if err != nil || !fi.IsRegular() || fi.Mode&0111 == 0 {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.SortStrings(names)
+	return names
+}
+
+// pluginCommand returns the file of the external command name, found in $PATH,
+// and the argument vector and environment to run it with args, or an empty
+// file if there is no such command.
+func pluginCommand(name string, args []string) (file string, argv, env []string) {
+	file, err := exec.LookPath(strings.Replace(commandPath(), " ", "-", -1) + "-" + name)
+	if err != nil || file == "" {
+		return "", nil, nil
+	}
+	env = os.Environ()
+	prefix := envName(progName()) + "_"
+	for _, f := range flags.order {
+		if f.Persistent {
+			env = append(env, prefix+envName(f.Name)+"="+f.Value.String())
+		}
+	}
+	return file, append([]string{file}, args...), env
+}
+
+// runPlugin runs the external command name, if there is one, with args and
+// exits with its exit status.  It returns if there is no such command.
+func runPlugin(name string, args []string) {
+	file, argv, env := pluginCommand(name, args)
+	if file == "" {
+		return
+	}
+	p, err := exec.Run(file, argv, env, "", exec.PassThrough, exec.PassThrough, exec.PassThrough)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(2)
+	}
+	w, err := p.Wait(0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+		os.Exit(2)
+	}
+	os.Exit(w.ExitStatus())
+}
+
+// envName turns s into the name of an environment variable, by upper-casing
+// it and replacing everything but letters and digits by underscores.
+func envName(s string) string {
+	return strings.Map(func(r int) int {
+		switch {
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		case 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// enter makes c the current command: it replaces the flags with a new set
+// for the command, defines its flags in it, and adds the persistent flags of
+// the set it replaces.
+func (c *Command) enter(path string) {
+	parent := flags
+	flags = newAllFlags()
+	flags.command = c
+	flags.path = path + " " + c.Name
+	flags.stop = c.dispatches()
+	flags.seen = parent.seen // the command line goes on
+	if c.Flags != nil {
+		c.Flags()
+	}
+	for _, f := range parent.order {
+		if f.Persistent {
+			flags.inherit(f, parent)
+		}
+	}
+}
+
+// inherit adds to f the persistent flag p of the set parent.  A command may
+// not define a flag with the long or short name of a persistent flag.
+func (f *allFlags) inherit(p *Flag, parent *allFlags) {
+	_, conflict := f.formal[p.Name]
+	if p.ShortName != "" {
+		r, _ := utf8.DecodeRuneInString(p.ShortName)
+		if _, ok := f.snames[r]; ok {
+			conflict = true
+		}
+		f.snames[r] = p.Name
+	}
+	if conflict {
+		fmt.Fprintln(os.Stderr, "flag redefines persistent flag:", p.Name)
+		panic("persistent flag redefinition")
+	}
+	f.formal[p.Name] = p
+	f.order = append(f.order, p)
+	if p.Group != nil {
+		found := false
+		for _, g := range f.groups {
+			found = found || g == p.Group
+		}
+		if !found {
+			f.groups = append(f.groups, p.Group)
+		}
+	}
+	switch p {
+	case parent.help:
+		f.help = p
+	case parent.printConfig:
+		f.printConfig = p
+	}
+}
+
+// Dispatch runs the command named by the first non-flag argument of the
+// command line, which must have been parsed by Parse.  The flags of the
+// command are defined and parsed from the rest of the arguments, and its Run
+// function is called with its non-flag arguments.  Commands with commands of
+// their own dispatch to those in the same way.
+//
+// The command "help", which all commands with subcommands have, prints the
+// usage message of the command named by its arguments to standard output.
+//
+// Once Dispatch has entered a command, functions such as Lookup, Args and
+// Usage refer to the flags and arguments of that command.
+func Dispatch() {
+	c := root
+	for c.dispatches() {
+		args := Args()
+		if len(args) == 0 {
+			fail(&Error{Kind: BadOperands, Msg: Gettext("missing command")})
+		}
+		if args[0] == "help" {
+			c.help(args[1:])
+			os.Exit(0)
+		}
+		sub := c.lookup(args[0])
+		if sub == nil && Plugins {
+			runPlugin(args[0], args[1:])
+		}
+		if sub == nil {
+			fail(&Error{Kind: InvalidValue, Value: args[0], Msg: fmt.Sprintf(Gettext("unknown command: %s"), args[0])})
+		}
+		offset := flags.rest + 1
+		sub.enter(commandPath())
+		flags.offset = offset
+		if err := flags.parse(args[1:]); err != nil {
+			fail(err)
+		}
+		c = sub
+	}
+	if c.Run != nil {
+		c.Run(Args())
+	}
+}
+
+// help prints to standard output the usage message of the command of c named
+// by the words of name.
+func (c *Command) help(name []string) {
+	for _, word := range name {
+		sub := c.lookup(word)
+		if sub == nil {
+			fmt.Fprintf(os.Stderr, Gettext("unknown command: %s")+"\n", word)
+			os.Exit(2)
+		}
+		sub.enter(commandPath())
+		c = sub
+	}
+	Output = os.Stdout
+	Usage()
+}
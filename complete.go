@@ -0,0 +1,399 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"container/vector"
+	"fmt"
+	"io"
+	"strings"
+	"utf8"
+)
+
+// A Completer returns the candidates for the argument of a flag that begin with
+// prefix.  A candidate may be followed by a tab and a short description.
+type Completer func(prefix string) []string
+
+// CompleteArg, if set, returns the candidates for the n'th non-flag argument
+// that begin with prefix.  When commands have been added, the arguments are
+// counted from the command name.
+var CompleteArg func(n int, prefix string) []string
+
+// CompletionArg is the hidden first argument that makes Parse print completions
+// rather than parse the command line.  The generated shell scripts invoke the
+// program as
+//
+//	prog __complete WORD... CURRENT
+//
+// where the WORDs are those already on the command line and CURRENT is the
+// (possibly empty) word being completed.  Candidates are printed one per line.
+var CompletionArg = "__complete"
+
+// SetCompleter sets the function that completes the argument of the named flag.
+// It returns false if there is no such flag defined.
+func SetCompleter(name string, c Completer) bool {
+	f, ok := flags.formal[name]
+	if !ok {
+		return false
+	}
+	f.Completer = c
+	return true
+}
+
+// Complete returns the completion candidates for the last of words, the
+// command line without the program name.  The preceding words are scanned by
+// the parser, as Parse and Dispatch would scan them but without setting any
+// flags, to find out whether an option, the argument of an option, a command
+// name or a non-flag argument is being completed.  Once a command is named,
+// its own flags are completed.
+func Complete(words []string) []string {
+	cur := ""
+	if len(words) > 0 {
+		cur = words[len(words)-1]
+		words = words[0 : len(words)-1]
+	}
+	defer func(saved *allFlags) { flags = saved }(flags)
+	flags.stop = root.dispatches()
+	c := root
+	pending, operands, terminated := flags.scan(words)
+	for pending == nil && flags.stop && len(operands) > 0 {
+		sub := c.lookup(operands[0])
+		if sub == nil {
+			return nil // an external command, or none at all
+		}
+		sub.enter(commandPath())
+		c = sub
+		pending, operands, terminated = flags.scan(operands[1:])
+	}
+	switch {
+	case pending != nil:
+		return completeValue(pending, cur)
+	case terminated || len(cur) == 0 || cur[0] != '-':
+		if flags.stop && len(operands) == 0 {
+			return completeCommands(c, cur)
+		}
+		if CompleteArg != nil {
+			return CompleteArg(len(operands), cur)
+		}
+		return nil
+	case cur == "-" || cur[1] == '-':
+		if i := strings.Index(cur, "="); i >= 0 {
+			if f, ok := flags.formal[cur[2:i]]; ok {
+				return completeValue(f, cur[i+1:])
+			}
+			return nil
+		}
+		return completeNames(cur)
+	}
+	if f, rest := flags.shortArg(cur[1:]); f != nil && rest != "" {
+		return completeValue(f, rest)
+	}
+	return []string{cur}
+}
+
+// scan runs the parser over words without setting any flags, for completion.
+// Words the parser rejects are skipped, as is everything after the name of a
+// command.  It returns the flag the next word would be the argument of, if
+// any, the non-flag arguments found and whether "--" ended the options.
+func (f *allFlags) scan(words []string) (pending *Flag, operands []string, terminated bool) {
+	args := f.args
+	f.args, f.dry = new(vector.StringVector), true
+	defer func() { f.args, f.dry = args, false }()
+	for i := 0; i < len(words); {
+		terminated = words[i] == "--"
+		ok, next, err := f.parseOne(words, i)
+		switch {
+		case err != nil && err.Kind == MissingArgument:
+			return f.lookupOption(err.Option), nil, false
+		case err != nil:
+			i++
+		case !ok:
+			i = len(words)
+		default:
+			i = next
+		}
+	}
+	return nil, f.args.Data(), terminated
+}
+
+// lookupOption returns the flag named by option, as "-x" or "--name", or nil.
+func (f *allFlags) lookupOption(option string) *Flag {
+	if strings.HasPrefix(option, "--") {
+		return f.formal[option[2:]]
+	}
+	sname, _ := utf8.DecodeRuneInString(option[1:])
+	return f.formal[f.snames[sname]]
+}
+
+// takesArg reports whether f requires an argument.
+func takesArg(f *Flag) bool {
+	_, ok := f.Value.(*boolValue)
+	return !ok
+}
+
+// shortArg scans a cluster of short flags and returns the first one that
+// takes an argument, along with the rest of the cluster, which is its
+// argument if not empty.  It returns nil if there is no such flag.
+func (f *allFlags) shortArg(cluster string) (*Flag, string) {
+	for i, sname := range cluster {
+		name, ok := f.snames[sname]
+		if !ok {
+			return nil, ""
+		}
+		if flag := f.formal[name]; takesArg(flag) {
+			return flag, cluster[i+utf8.RuneLen(sname):]
+		}
+	}
+	return nil, ""
+}
+
+// completeNames returns the flags whose long (or, for a lone "-", short)
+// names begin with prefix, each followed by its usage string.
+func completeNames(prefix string) []string {
+	var c []string
+	for _, f := range flags.order {
+		if !listed(f) {
+			continue
+		}
+		usage := completionUsage(f)
+		if prefix == "-" && f.ShortName != "" {
+			c = append(c, "-"+f.ShortName+"\t"+usage)
+		}
+		if strings.HasPrefix("--"+f.Name, prefix) {
+			c = append(c, "--"+f.Name+"\t"+usage)
+		}
+	}
+	return c
+}
+
+// completionUsage returns the usage string of f as shown by shell completion.
+// Completion lists flags without the headings of their option groups, so the
+// group title comes first: "Compression options: filter through gzip".
+func completionUsage(f *Flag) string {
+	_, usage := unquoteUsage(f)
+	if f.Group == nil || usage == "" {
+		return usage
+	}
+	return strings.TrimRight(Gettext(f.Group.Title), ": ") + ": " + usage
+}
+
+// completeCommands returns the commands of c whose names begin with prefix,
+// each followed by its summary, and the external commands if c dispatches to
+// them.
+func completeCommands(c *Command, prefix string) []string {
+	var names []string
+	for _, sub := range c.commands {
+		if strings.HasPrefix(sub.Name, prefix) {
+			if sub.Summary != "" {
+				names = append(names, sub.Name+"\t"+sub.Summary)
+			} else {
+				names = append(names, sub.Name)
+			}
+		}
+	}
+	if Plugins && c == root {
+		for _, name := range plugins(commandPath()) {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// completeValue returns the candidates for the argument of f that begin with
+// prefix.
+func completeValue(f *Flag, prefix string) []string {
+	if f.Completer != nil {
+		return f.Completer(prefix)
+	}
+	var c []string
+	if e, ok := f.Value.(*enumValue); ok {
+		for _, choice := range e.choices {
+			if strings.HasPrefix(choice, prefix) {
+				c = append(c, choice)
+			}
+		}
+	}
+	return c
+}
+
+// ZshCompletion writes to w a zsh completion function for the program prog,
+// generated from the defined flags.  The descriptions of flags in option groups
+// begin with the group title.  The output is meant to be installed as
+// the file _prog somewhere in $fpath.
+func ZshCompletion(w io.Writer, prog string) {
+	fmt.Fprintf(w, "#compdef %s\n\n", prog)
+	fmt.Fprintf(w, "_%s() {\n", prog)
+	fmt.Fprintf(w, "\tlocal -a args\n\targs=(\n")
+	visitGroups(func(g *OptionGroup, group []*Flag) {
+		if g != nil {
+			fmt.Fprintf(w, "\t\t# %s\n", g.Title)
+		}
+		for _, f := range group {
+			fmt.Fprintf(w, "\t\t%s\n", zshSpec(f, prog))
+		}
+	})
+	if CompleteArg != nil {
+		fmt.Fprintf(w, "\t\t'*:argument:_%s_complete'\n", prog)
+	} else {
+		fmt.Fprintf(w, "\t\t'*:file:_files'\n")
+	}
+	fmt.Fprintf(w, "\t)\n\t_arguments -s -S $args\n")
+	fmt.Fprintf(w, "}\n\n")
+	// Ask the program itself for dynamic completions.  Candidates come back
+	// as "value<TAB>description", which _describe wants as "value:description".
+	fmt.Fprintf(w, "_%s_complete() {\n", prog)
+	fmt.Fprintf(w, "\tlocal -a candidates\n")
+	fmt.Fprintf(w, "\tcandidates=(${(f)\"$(${words[1]} %s \"${(@)words[2,CURRENT]}\" 2>/dev/null)\"})\n", CompletionArg)
+	fmt.Fprintf(w, "\tcandidates=(${candidates//:/\\\\:})\n")
+	fmt.Fprintf(w, "\tcandidates=(${candidates//$'\\t'/:})\n")
+	fmt.Fprintf(w, "\t_describe -t values value candidates\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "_%s \"$@\"\n", prog)
+}
+
+// zshSpec returns the _arguments specification for a single flag.  Flags with
+// both a short and a long name exclude each other, so that zsh doesn't offer
+// one once the other has been given; repeatable flags are offered again.
+func zshSpec(f *Flag, prog string) string {
+	desc := "[" + zshEscape(completionUsage(f)) + "]"
+	excl, repeat := "(-"+f.ShortName+" --"+f.Name+")", ""
+	if repeatable(f) {
+		excl, repeat = "*", "*"
+	}
+	if _, ok := f.Value.(*boolValue); ok {
+		if f.ShortName == "" {
+			return zshQuote(repeat + "--" + f.Name + desc)
+		}
+		return zshQuote(excl) + "{-" + f.ShortName + ",--" + f.Name + "}" + zshQuote(desc)
+	}
+	arg := ":" + zshEscape(f.Name) + ":" + zshAction(f, prog)
+	if f.ShortName == "" {
+		return zshQuote(repeat + "--" + f.Name + "=" + desc + arg)
+	}
+	return zshQuote(excl) + "{-" + f.ShortName + "+,--" + f.Name + "=}" + zshQuote(desc+arg)
+}
+
+// zshAction returns the _arguments action used to complete the argument of f.
+func zshAction(f *Flag, prog string) string {
+	if f.Completer != nil {
+		return "_" + prog + "_complete"
+	}
+	switch v := f.Value.(type) {
+	case *enumValue:
+		choices := make([]string, len(v.choices))
+		for i, c := range v.choices {
+			choices[i] = zshEscapeWord(c)
+		}
+		return "(" + strings.Join(choices, " ") + ")"
+	case *stringValue, *stringsValue:
+		return "_files"
+	case *pathValue:
+		return v.opts.zsh()
+	case *pathsValue:
+		return v.opts.zsh()
+	}
+	// Nothing sensible to offer; just show the message.
+	return " "
+}
+
+// zshEscape escapes the characters that are special inside an _arguments
+// description.
+func zshEscape(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	s = strings.Replace(s, "[", "\\[", -1)
+	s = strings.Replace(s, "]", "\\]", -1)
+	return strings.Replace(s, ":", "\\:", -1)
+}
+
+// zshEscapeWord escapes a single word of an _arguments action list.
+func zshEscapeWord(s string) string {
+	s = zshEscape(s)
+	for _, c := range []string{" ", "(", ")"} {
+		s = strings.Replace(s, c, "\\"+c, -1)
+	}
+	return s
+}
+
+// zshQuote single-quotes s for the shell.
+func zshQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "'\\''", -1) + "'"
+}
+
+// FishCompletion writes to w a fish script of 'complete' commands for the
+// program prog, generated from the defined flags.  As with ZshCompletion, the
+// descriptions of flags in option groups begin with the group title.
+func FishCompletion(w io.Writer, prog string) {
+	dynamic := fishQuote("(__" + prog + "_complete)")
+	fmt.Fprintf(w, "function __%s_complete\n", prog)
+	fmt.Fprintf(w, "\tset -l words (commandline -opc)\n")
+	fmt.Fprintf(w, "\t$words[1] %s $words[2..-1] (commandline -ct)\n", CompletionArg)
+	fmt.Fprintf(w, "end\n\n")
+	if CompleteArg != nil {
+		fmt.Fprintf(w, "complete -c %s -a %s\n", fishQuote(prog), dynamic)
+	}
+	visitGroups(func(g *OptionGroup, group []*Flag) {
+		if g != nil {
+			fmt.Fprintf(w, "\n# %s\n", g.Title)
+		}
+		for _, f := range group {
+			fishComplete(w, prog, f, dynamic)
+		}
+	})
+}
+
+// fishComplete writes the complete command for f.  The command dynamic
+// completes its argument by asking the program, if f has a Completer.
+func fishComplete(w io.Writer, prog string, f *Flag, dynamic string) {
+	line := "complete -c " + fishQuote(prog)
+	if f.ShortName != "" {
+		line += " -s " + fishQuote(f.ShortName)
+	}
+	line += " -l " + fishQuote(f.Name)
+	if f.Completer != nil && takesArg(f) {
+		line += " -x -a " + dynamic
+	} else {
+		switch v := f.Value.(type) {
+		case *boolValue:
+			// Takes no argument.
+		case *enumValue:
+			line += " -x -a " + fishQuote(strings.Join(v.choices, " "))
+		case *stringValue, *stringsValue:
+			line += " -r"
+		case *pathValue:
+			line += " " + v.opts.fish()
+		case *pathsValue:
+			line += " " + v.opts.fish()
+		default:
+			line += " -x"
+		}
+	}
+	if usage := completionUsage(f); usage != "" {
+		line += " -d " + fishQuote(usage)
+	}
+	fmt.Fprintln(w, line)
+}
+
+// fishQuote single-quotes s for fish, which only knows the \\ and \' escapes
+// inside single quotes.
+func fishQuote(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	return "'" + strings.Replace(s, "'", "\\'", -1) + "'"
+}
@@ -0,0 +1,272 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Now returns the current time, in seconds since the Unix epoch.  Relative
+// dates are relative to it; tests may replace it.
+var Now = time.Seconds
+
+// weekdays are the names of the days of the week, from Sunday.
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// A dateUnit is a unit of relative dates: a number of months, days or
+// seconds.
+type dateUnit struct {
+	months, days int
+	seconds      int64
+}
+
+var dateUnits = map[string]dateUnit{
+	"year":      dateUnit{months: 12},
+	"month":     dateUnit{months: 1},
+	"fortnight": dateUnit{days: 14},
+	"week":      dateUnit{days: 7},
+	"day":       dateUnit{days: 1},
+	"hour":      dateUnit{seconds: 3600},
+	"minute":    dateUnit{seconds: 60},
+	"min":       dateUnit{seconds: 60},
+	"second":    dateUnit{seconds: 1},
+	"sec":       dateUnit{seconds: 1},
+}
+
+// dateNumbers are the words that may stand for the number of a relative item
+// or the ordinal of a day of the week.
+var dateNumbers = map[string]int{"last": -1, "this": 0, "next": 1}
+
+// A dateExpr is a date expression being parsed.
+type dateExpr struct {
+	year             int64
+	month, day       int
+	hour, min, sec   int
+	dateSet, timeSet bool
+	weekday, ordinal int // the day of the week given, if weekday >= 0
+	months, days     int // relative items
+	seconds          int64
+	last             *dateUnit // the last relative item, for "ago"
+	lastN            int
+}
+
+// parseDate parses s as GNU date -d does, as a time of the kind parse accepts
+// or as a combination of the items
+//
+//	2024-03-01        a date, at midnight unless a time is given
+//	17:00, 17:00:30   a time of day
+//	monday, next fri  a day of the week, at midnight; "last" and "next"
+//	                  move a week back or on, or a number that many weeks
+//	3 days ago        a relative item in years, months, fortnights, weeks,
+//	                  days, hours, minutes or seconds; "ago" makes it go
+//	                  back, as do "last" and negative numbers
+//	now, today, yesterday, tomorrow, noon, midnight
+//
+// relative to the time returned by Now.
+func (o *TimeOptions) parseDate(s string) (*time.Time, bool) {
+	if t, ok := o.parse(s); ok {
+		return t, true
+	}
+	now := o.in(Now())
+	e := &dateExpr{year: now.Year, month: now.Month, day: now.Day,
+		hour: now.Hour, min: now.Minute, sec: now.Second, weekday: -1}
+	words := strings.Fields(strings.ToLower(strings.Replace(s, ",", " ", -1)))
+	if len(words) == 0 {
+		return nil, false
+	}
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		n, isNumber := dateNumbers[w]
+		if !isNumber {
+			if v, err := strconv.Atoi(w); err == nil {
+				n, isNumber = v, true
+			}
+		}
+		if isNumber {
+			// A number is followed by a unit or a day of the week.
+			if i++; i == len(words) || !e.unit(words[i], n) && !e.day(words[i], n) {
+				return nil, false
+			}
+			continue
+		}
+		if !e.word(w) && !e.unit(w, 1) && !e.day(w, 0) && !e.date(w) && !e.clock(w) {
+			return nil, false
+		}
+	}
+	return o.in(e.resolve(o)), true
+}
+
+// word applies w if it is a word that stands alone.
+func (e *dateExpr) word(w string) bool {
+	switch w {
+	case "now", "today":
+	case "yesterday":
+		e.days--
+	case "tomorrow":
+		e.days++
+	case "midnight":
+		e.hour, e.min, e.sec, e.timeSet = 0, 0, 0, true
+	case "noon":
+		e.hour, e.min, e.sec, e.timeSet = 12, 0, 0, true
+	case "ago":
+		if e.last == nil {
+			return false
+		}
+		e.add(e.last, -2*e.lastN) // undo the item, then go back as far
+		e.last = nil
+	default:
+		return false
+	}
+	return true
+}
+
+// unit applies w if it is a unit of relative items, n of which are wanted.
+func (e *dateExpr) unit(w string, n int) bool {
+	u, ok := dateUnits[w]
+	if !ok && strings.HasSuffix(w, "s") {
+		u, ok = dateUnits[w[0:len(w)-1]]
+	}
+	if !ok {
+		return false
+	}
+	e.add(&u, n)
+	e.last, e.lastN = &u, n
+	return true
+}
+
+// add adds n of the unit u to the relative items of e.
+func (e *dateExpr) add(u *dateUnit, n int) {
+	e.months += n * u.months
+	e.days += n * u.days
+	e.seconds += int64(n) * u.seconds
+}
+
+// day applies w if it is the name of a day of the week, or its first three
+// letters, with the given ordinal.
+func (e *dateExpr) day(w string, ordinal int) bool {
+	for i, name := range weekdays {
+		if w == name || w == name[0:3] {
+			e.weekday, e.ordinal = i, ordinal
+			return true
+		}
+	}
+	return false
+}
+
+// date applies w if it is a date, YYYY-MM-DD.
+func (e *dateExpr) date(w string) bool {
+	t, err := time.Parse("2006-01-02", w)
+	if err != nil {
+		return false
+	}
+	e.year, e.month, e.day, e.dateSet = t.Year, t.Month, t.Day, true
+	return true
+}
+
+// clock applies w if it is a time of day, HH:MM or HH:MM:SS.
+func (e *dateExpr) clock(w string) bool {
+	parts := strings.Split(w, ":", -1)
+	if len(parts) < 2 || len(parts) > 3 {
+		return false
+	}
+	v := []int{0, 0, 0}
+	max := []int{23, 59, 59}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || len(p) != 2 && i > 0 || n < 0 || n > max[i] {
+			return false
+		}
+		v[i] = n
+	}
+	e.hour, e.min, e.sec, e.timeSet = v[0], v[1], v[2], true
+	return true
+}
+
+// resolve returns the time e stands for, in seconds since the Unix epoch, when
+// read in the zone of o.
+func (e *dateExpr) resolve(o *TimeOptions) int64 {
+	y, m, d := e.year, e.month, e.day
+	h, min, sec := e.hour, e.min, e.sec
+	if (e.dateSet || e.weekday >= 0) && !e.timeSet {
+		h, min, sec = 0, 0, 0
+	}
+	if e.weekday >= 0 {
+		// As GNU date does: the day itself or the next one of its name,
+		// moved by whole weeks for an ordinal, except that "next" (1)
+		// from another day is the next one.
+		wday := int((civilDays(y, m, d)%7 + 11) % 7)
+		d += (e.weekday - wday + 7) % 7
+		if e.ordinal > 0 && wday != e.weekday {
+			d += 7 * (e.ordinal - 1)
+		} else {
+			d += 7 * e.ordinal
+		}
+	}
+	days := civilDays(y, m+e.months, d+e.days)
+	wall := days*86400 + int64(h*3600+min*60+sec) + e.seconds
+	return wall - o.offset(wall)
+}
+
+// civilDays returns the number of days from 1970-01-01 to the date y-m-d in
+// the proleptic Gregorian calendar.  Months and days out of range carry into
+// the years and months, so that January 32 is February 1.
+func civilDays(y int64, m, d int) int64 {
+	// Bring the month in range.
+	y += int64((m - 1) / 12)
+	m = (m-1)%12 + 1
+	if m < 1 {
+		y, m = y-1, m+12
+	}
+	// Count from March 1, 0000, so that leap days end the years.
+	if m <= 2 {
+		y--
+	}
+	era := y / 400
+	if y < 0 {
+		era = (y - 399) / 400
+	}
+	yoe := y - era*400
+	mp := (m + 9) % 12 // March is 0
+	doy := int64((153*mp+2)/5 + d - 1)
+	doe := yoe*365 + yoe/4 - yoe/100 + doy
+	return era*146097 + doe - 719468
+}
+
+// DateVar defines a time flag with specified name, default value, and usage
+// string, which accepts as well as the times Time flags accept the relative
+// dates of GNU date -d, as in "2 days ago", "yesterday 17:00", "last monday" or
+// "2024-03-01 +3 weeks".  They are relative to the time returned by Now.  The
+// argument p points to a time.Time variable in which to store the value of the
+// flag.
+func DateVar(p *time.Time, name, shortName string, value time.Time, opts TimeOptions, usage string) {
+	v := newTimeValue(value, opts, p)
+	v.relative = true
+	add(name, shortName, v, usage)
+}
+
+// Date defines a time flag with specified name, default value, and usage string,
+// which accepts relative dates as those of DateVar do.  The return value is the
+// address of a time.Time variable that stores the value of the flag.
+func Date(name, shortName string, value time.Time, opts TimeOptions, usage string) *time.Time {
+	p := new(time.Time)
+	DateVar(p, name, shortName, value, opts, usage)
+	return p
+}
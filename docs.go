@@ -0,0 +1,185 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// optionString returns the command-line form of f, e.g. "-o, --output=FILE".
+func optionString(f *Flag, arg string) string {
+	s := "--" + f.Name
+	if f.ShortName != "" {
+		s = "-" + f.ShortName + ", " + s
+	}
+	if arg != "" {
+		s += "=" + arg
+	}
+	return s
+}
+
+// optionAnchor returns the fragment identifier under which the reference
+// documentation of f can be linked to.
+func optionAnchor(f *Flag) string { return "option-" + f.Name }
+
+// Markdown writes to w an option reference page for the program p in Markdown,
+// with one section for each defined flag.  Each flag is anchored as
+// #option-NAME so that other pages can link to it.
+func Markdown(w io.Writer, p *Program) {
+	name := p.name()
+	fmt.Fprintf(w, "# %s\n\n", markdownEscape(name))
+	if p.Summary != "" {
+		fmt.Fprintf(w, "%s\n\n", markdownEscape(p.Summary))
+	}
+	fmt.Fprintf(w, "## Synopsis\n\n    %s\n\n", strings.TrimSpace(name+" [OPTION]... "+p.synopsis()))
+	if p.Description != "" {
+		fmt.Fprintf(w, "## Description\n\n%s\n\n", markdownEscape(strings.TrimSpace(p.Description)))
+	}
+	if len(flags.order) > 0 {
+		fmt.Fprintf(w, "## Options\n\n")
+		visitGroups(func(g *OptionGroup, group []*Flag) {
+			heading := "###"
+			if g != nil {
+				fmt.Fprintf(w, "### %s\n\n", markdownEscape(g.Title))
+				heading = "####"
+			}
+			for _, f := range group {
+				markdownOption(w, f, heading)
+			}
+		})
+	}
+	markdownEntries(w, "Environment", p.environment())
+	markdownEntries(w, "Files", p.Files)
+}
+
+// markdownOption writes the reference for f under a heading of the given level.
+func markdownOption(w io.Writer, f *Flag, heading string) {
+	arg, usage := unquoteUsage(f)
+	fmt.Fprintf(w, "<a id=\"%s\"></a>\n", optionAnchor(f))
+	fmt.Fprintf(w, "%s `%s`\n\n", heading, optionString(f, arg))
+	if usage != "" {
+		fmt.Fprintf(w, "%s\n\n", markdownEscape(usage))
+	}
+	fmt.Fprintf(w, "* Type: %s\n", typeName(f))
+	fmt.Fprintf(w, "* Default: `%s`\n", defaultString(f))
+	if f.Env != "" {
+		fmt.Fprintf(w, "* Environment: `%s`\n", f.Env)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// markdownEntries writes a section listing entries.
+func markdownEntries(w io.Writer, section string, entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "## %s\n\n", section)
+	for _, e := range entries {
+		fmt.Fprintf(w, "* `%s`: %s\n", e.Name, markdownEscape(e.Description))
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// markdownEscape escapes the characters of s that Markdown would take as
+// formatting.
+func markdownEscape(s string) string {
+	for _, c := range []string{"\\", "`", "*", "_", "[", "]", "<", ">", "#"} {
+		s = strings.Replace(s, c, "\\"+c, -1)
+	}
+	return s
+}
+
+// htmlStyle is the style sheet embedded in pages written by HTML, so that they
+// need nothing else to display properly.
+const htmlStyle = `body { font-family: sans-serif; max-width: 50em; margin: 2em auto; }
+dt { font-weight: bold; margin-top: 1em; }
+dd p.meta { color: #555; font-size: smaller; }
+`
+
+// HTML writes to w a self-contained HTML page equivalent to the one written by
+// Markdown, with the same #option-NAME anchors.
+func HTML(w io.Writer, p *Program) {
+	name := htmlEscape(p.name())
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(w, "<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n", name, htmlStyle)
+	fmt.Fprintf(w, "<h1>%s</h1>\n", name)
+	if p.Summary != "" {
+		fmt.Fprintf(w, "<p>%s</p>\n", htmlEscape(p.Summary))
+	}
+	fmt.Fprintf(w, "<h2 id=\"synopsis\">Synopsis</h2>\n<pre>%s</pre>\n", htmlEscape(strings.TrimSpace(p.name()+" [OPTION]... "+p.synopsis())))
+	if p.Description != "" {
+		fmt.Fprintf(w, "<h2 id=\"description\">Description</h2>\n")
+		for _, para := range strings.Split(strings.TrimSpace(p.Description), "\n\n", -1) {
+			fmt.Fprintf(w, "<p>%s</p>\n", htmlEscape(para))
+		}
+	}
+	if len(flags.order) > 0 {
+		fmt.Fprintf(w, "<h2 id=\"options\">Options</h2>\n")
+		visitGroups(func(g *OptionGroup, group []*Flag) {
+			if g != nil {
+				fmt.Fprintf(w, "<h3>%s</h3>\n", htmlEscape(g.Title))
+			}
+			fmt.Fprintf(w, "<dl>\n")
+			for _, f := range group {
+				htmlOption(w, f)
+			}
+			fmt.Fprintf(w, "</dl>\n")
+		})
+	}
+	htmlEntries(w, "Environment", p.environment())
+	htmlEntries(w, "Files", p.Files)
+	fmt.Fprintf(w, "</body>\n</html>\n")
+}
+
+// htmlOption writes the reference for f as an entry of a definition list.
+func htmlOption(w io.Writer, f *Flag) {
+	arg, usage := unquoteUsage(f)
+	fmt.Fprintf(w, "<dt id=\"%s\"><code>%s</code></dt>\n", optionAnchor(f), htmlEscape(optionString(f, arg)))
+	fmt.Fprintf(w, "<dd>")
+	if usage != "" {
+		fmt.Fprintf(w, "<p>%s</p>", htmlEscape(usage))
+	}
+	fmt.Fprintf(w, "<p class=\"meta\">Type: %s; default: <code>%s</code>", typeName(f), htmlEscape(defaultString(f)))
+	if f.Env != "" {
+		fmt.Fprintf(w, "; environment: <code>%s</code>", htmlEscape(f.Env))
+	}
+	fmt.Fprintf(w, "</p></dd>\n")
+}
+
+// htmlEntries writes a section listing entries.
+func htmlEntries(w io.Writer, section string, entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "<h2 id=\"%s\">%s</h2>\n<dl>\n", strings.ToLower(section), section)
+	for _, e := range entries {
+		fmt.Fprintf(w, "<dt><code>%s</code></dt>\n<dd>%s</dd>\n", htmlEscape(e.Name), htmlEscape(e.Description))
+	}
+	fmt.Fprintf(w, "</dl>\n")
+}
+
+// htmlEscape escapes the characters of s that are special in HTML.
+func htmlEscape(s string) string {
+	s = strings.Replace(s, "&", "&amp;", -1)
+	s = strings.Replace(s, "<", "&lt;", -1)
+	s = strings.Replace(s, ">", "&gt;", -1)
+	return strings.Replace(s, "\"", "&quot;", -1)
+}
@@ -0,0 +1,173 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import "fmt"
+
+// A DupPolicy says what giving a flag more than once does.
+type DupPolicy int
+
+const (
+	// DefaultDup leaves the policy to the flag set.  For a set, it is
+	// LastWins, except for list flags, which Accumulate.
+	DefaultDup DupPolicy = iota
+	// LastWins makes each value replace the one before, as with GNU tools,
+	// so that "ls --color=auto --color=never" lists without color.  List
+	// flags then hold only the last value.
+	LastWins
+	// FirstWins ignores the values after the first.
+	FirstWins
+	// DupError rejects the command line with "flag specified twice".
+	DupError
+	// Accumulate adds each value to a list flag, as long as the values come
+	// from the same source: a list given on the command line replaces one
+	// from the environment or the default.  Flags holding a single value
+	// take the last one, as with LastWins.
+	Accumulate
+)
+
+// SetDupPolicy sets what giving a flag more than once does for the flags of
+// the set being defined, those of the program or, when called from the Flags
+// function of a command, those of the command.  Flags with a policy of their
+// own keep it.
+func SetDupPolicy(p DupPolicy) { flags.dup = p }
+
+// SetFlagDupPolicy sets what giving the named flag more than once does.  It
+// returns false if there is no such flag defined.
+func SetFlagDupPolicy(name string, p DupPolicy) bool {
+	f, ok := flags.formal[name]
+	if !ok {
+		return false
+	}
+	f.Dup = p
+	return true
+}
+
+// policy returns the policy in effect for flag.
+func (f *allFlags) policy(flag *Flag) DupPolicy {
+	p := flag.Dup
+	if p == DefaultDup {
+		p = f.dup
+	}
+	if p == DefaultDup {
+		if isList(flag.Value) {
+			return Accumulate
+		}
+		return LastWins
+	}
+	return p
+}
+
+// apply sets flag to value, which came from src, following the policy of flag
+// if it has already been set from the same source since the command line was
+// begun: the command line, the same configuration file or the program.  Values
+// from different sources replace each other in the order they are applied,
+// lists included, so that the command line overrides a list taken from the
+// environment or the default.  The option is the flag as given, for errors.
+func (f *allFlags) apply(flag *Flag, option, value string, src Source) *Error {
+	if f.dry {
+		return nil
+	}
+	policy := f.policy(flag)
+	s, ok := f.seen[flag.Value]
+	repeated := ok && s.Kind == src.Kind && s.Name == src.Name
+	if repeated {
+		switch policy {
+		case DupError:
+			return &Error{Kind: RepeatedOption, Option: option,
+				Msg: fmt.Sprintf(Gettext("flag specified twice: %s"), option)}
+		case FirstWins:
+			return nil
+		}
+	}
+	// Lists accumulate the values of one source only.
+	undo := func() {}
+	if policy == LastWins || !repeated {
+		undo = resetList(flag.Value)
+	}
+	if !flag.Value.set(value) {
+		undo()
+		msg := Gettext("invalid value %s for flag: %s")
+		if _, ok := flag.Value.(*boolValue); ok {
+			msg = Gettext("invalid boolean value %s for flag: %s")
+		}
+		err := &Error{Kind: InvalidValue, Option: option, Value: value, Msg: fmt.Sprintf(msg, value, option)}
+		if r, ok := flag.Value.(reasoner); ok {
+			err.Err = r.reason()
+		}
+		return err
+	}
+	f.seen[flag.Value] = src
+	flag.Source = src
+	// A deprecated alias sets its replacement too.
+	if r, ok := f.formal[flag.Replacement]; ok && r.Value == flag.Value {
+		r.Source = src
+	}
+	return nil
+}
+
+// mark records that flag has been set, along with the flag it is a deprecated
+// alias for.
+func (f *allFlags) mark(flag *Flag) {
+	if f.dry {
+		return
+	}
+	f.actual[flag.Name] = flag
+	if r, ok := f.formal[flag.Replacement]; ok && r.Value == flag.Value {
+		f.actual[r.Name] = r
+	}
+}
+
+// resetList empties v if it is a list value, and returns a function that puts
+// back what it held.
+func resetList(v FlagValue) (undo func()) {
+	undo = func() {}
+	switch v := v.(type) {
+	case *stringsValue:
+		old := *v.p
+		*v.p, undo = nil, func() { *v.p = old }
+	case *intsValue:
+		old := *v.p
+		*v.p, undo = nil, func() { *v.p = old }
+	case *int64sValue:
+		old := *v.p
+		*v.p, undo = nil, func() { *v.p = old }
+	case *uintsValue:
+		old := *v.p
+		*v.p, undo = nil, func() { *v.p = old }
+	case *uint64sValue:
+		old := *v.p
+		*v.p, undo = nil, func() { *v.p = old }
+	case *float64sValue:
+		old := *v.p
+		*v.p, undo = nil, func() { *v.p = old }
+	case *prefixesValue:
+		old := *v.p
+		*v.p, undo = nil, func() { *v.p = old }
+	case *regexpsValue:
+		old, sources := *v.p, v.sources
+		*v.p, v.sources = nil, nil
+		undo = func() { *v.p, v.sources = old, sources }
+	case *pathsValue:
+		old, raw := *v.p, v.raw
+		*v.p, v.raw = nil, nil
+		undo = func() { *v.p, v.raw = old, raw }
+	}
+	return
+}
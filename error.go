@@ -0,0 +1,223 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"utf8"
+)
+
+// GNUDiagnostics makes errors read exactly as those of GNU getopt, as in
+//
+//	prog: unrecognized option '--foo'
+//	prog: invalid option -- 'x'
+//	prog: option requires an argument -- 'x'
+//	prog: option '--verbose' doesn't allow an argument
+//	Try 'prog --help' for more information.
+//
+// Parse then prints the name of the program before the error and the last
+// line, if the --help flag is defined, instead of the usage message.  Like
+// getopt, it also rejects values given to boolean flags with "=".
+var GNUDiagnostics = false
+
+// Opterr, like getopt's opterr, controls whether Parse and Dispatch print
+// errors.  If false, they exit with status 2 without printing anything;
+// ParseArgs still returns the error.
+var Opterr = true
+
+// An ErrorKind says what is wrong with a command line.
+type ErrorKind int
+
+const (
+	InvalidValue       ErrorKind = iota // the value of an option or operand is invalid
+	UnknownOption                       // the option is not defined
+	MissingArgument                     // the option requires an argument, which is missing
+	UnexpectedArgument                  // the option doesn't allow an argument, but was given one
+	RepeatedOption                      // the option was given more than once
+	BadSyntax                           // the argument is not a well-formed option
+	BadOperands                         // there are too many or too few operands
+)
+
+// An Error describes an invalid command line.  ParseArgs returns one; Parse
+// prints it and exits.
+type Error struct {
+	Kind        ErrorKind
+	Option      string   // the offending option as given, e.g. "--verbsoe"; empty if none
+	Value       string   // the offending value, if any
+	Msg         string   // what is wrong
+	Suggestions []string // the options that might have been meant, best first
+	Err         os.Error // why the value was rejected, if known, e.g. a regexp syntax error
+}
+
+// String returns the message, followed by the suggestions if there are any:
+//
+//	unrecognized option '--verbsoe'; did you mean '--verbose'?
+//
+// or by the reason the value was rejected, if known:
+//
+//	invalid value a( for flag: --regexp: regexp: unmatched '('
+//
+// If GNUDiagnostics is set, it returns the message getopt would print
+// instead, without the program name.
+func (e *Error) String() string {
+	if GNUDiagnostics {
+		return e.gnu()
+	}
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.String()
+	}
+	if len(e.Suggestions) == 0 {
+		return e.Msg
+	}
+	n := len(e.Suggestions)
+	s := "'" + e.Suggestions[n-1] + "'"
+	if n > 1 {
+		s = fmt.Sprintf(Gettext("%s or %s"), "'"+strings.Join(e.Suggestions[0:n-1], "', '")+"'", s)
+	}
+	return fmt.Sprintf(Gettext("%s; did you mean %s?"), e.Msg, s)
+}
+
+// gnu returns the message of e as worded by GNU getopt, or by the coreutils
+// for invalid values.
+func (e *Error) gnu() string {
+	long := strings.HasPrefix(e.Option, "--")
+	switch {
+	case e.Option == "":
+		break
+	case e.Kind == UnknownOption && long:
+		return fmt.Sprintf(Gettext("unrecognized option '%s'"), e.Option)
+	case e.Kind == UnknownOption:
+		return fmt.Sprintf(Gettext("invalid option -- '%s'"), e.Option[1:])
+	case e.Kind == MissingArgument && long:
+		return fmt.Sprintf(Gettext("option '%s' requires an argument"), e.Option)
+	case e.Kind == MissingArgument:
+		return fmt.Sprintf(Gettext("option requires an argument -- '%s'"), e.Option[1:])
+	case e.Kind == UnexpectedArgument:
+		return fmt.Sprintf(Gettext("option '%s' doesn't allow an argument"), e.Option)
+	case e.Kind == InvalidValue:
+		return fmt.Sprintf(Gettext("invalid argument '%s' for '%s'"), e.Value, e.Option)
+	}
+	return e.Msg
+}
+
+// A candidate is a possible suggestion and how far it is from what was given.
+type candidate struct {
+	option   string
+	distance int
+}
+
+// suggest returns the long options that might have been meant by the unknown
+// long option name: those it is a prefix of and those within a small edit
+// distance of it, nearest first.
+func (f *allFlags) suggest(name string) []string {
+	max := utf8.RuneCountInString(name) / 3
+	if max < 1 {
+		max = 1
+	}
+	var c []candidate
+	for _, flag := range f.order {
+		if !listed(flag) {
+			continue
+		}
+		d := distance(name, flag.Name)
+		if name != "" && strings.HasPrefix(flag.Name, name) {
+			d = 0
+		}
+		if d > max {
+			continue
+		}
+		// Insert in order of distance, keeping the order of definition
+		// among equals.
+		i := len(c)
+		c = append(c, candidate{})
+		for ; i > 0 && c[i-1].distance > d; i-- {
+			c[i] = c[i-1]
+		}
+		c[i] = candidate{"--" + flag.Name, d}
+	}
+	s := make([]string, len(c))
+	for i := range c {
+		s[i] = c[i].option
+	}
+	return s
+}
+
+// suggestShort returns the options that might have been meant by the unknown
+// short option sname: a short option differing only in case and the long
+// options suggest finds for word, the argument sname is part of without its
+// dash, since "-verbose" is likely meant to be "--verbose".
+func (f *allFlags) suggestShort(sname int, word string) []string {
+	var s []string
+	for _, r := range []int{unicode.ToLower(sname), unicode.ToUpper(sname)} {
+		if r == sname {
+			continue
+		}
+		if name, ok := f.snames[r]; ok && listed(f.formal[name]) {
+			s = append(s, "-"+string(r))
+		}
+	}
+	if i := strings.Index(word, "="); i >= 0 {
+		word = word[0:i]
+	}
+	if utf8.RuneCountInString(word) > 1 {
+		s = append(s, f.suggest(word)...)
+	}
+	return s
+}
+
+// distance returns the number of single-character insertions, deletions,
+// substitutions and transpositions of adjacent characters needed to turn a
+// into b.
+func distance(a, b string) int {
+	s, t := []int(a), []int(b)
+	// d[i][j] is the distance between s[0:i] and t[0:j].
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+func min(n int, rest ...int) int {
+	for _, m := range rest {
+		if m < n {
+			n = m
+		}
+	}
+	return n
+}
@@ -0,0 +1,255 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Messages maps messages to their translations.  Gettext looks messages up in
+// it; LoadCatalog adds to it.  Messages that are missing stay in English.
+//
+// The messages of gnuflag itself, such as its errors and the headings of the
+// usage message, are translated, as are the usage strings of flags, the
+// titles of option groups and the summaries of commands, so that an
+// application's catalog can cover those.
+var Messages map[string]string
+
+// Gettext returns the translation of msgid in Messages, or msgid if there is
+// none.
+func Gettext(msgid string) string {
+	if s, ok := Messages[msgid]; ok && s != "" {
+		return s
+	}
+	return msgid
+}
+
+// Languages returns the languages messages should be translated into, best
+// first, selected as gettext does: those in $LANGUAGE, a colon-separated
+// list, followed by the locale, the first of $LC_ALL, $LC_MESSAGES and $LANG
+// that is set.  A language with a territory, as in "de_DE", is followed by
+// the language alone, as in "de".  If the locale is "C" or "POSIX", or not
+// set, messages are in English and Languages returns nothing.
+func Languages() []string {
+	locale := ""
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if locale = os.Getenv(name); locale != "" {
+			break
+		}
+	}
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+	var langs []string
+	seen := make(map[string]bool)
+	add := func(lang string) {
+		if lang != "" && !seen[lang] {
+			seen[lang] = true
+			langs = append(langs, lang)
+		}
+	}
+	for _, l := range append(strings.Split(os.Getenv("LANGUAGE"), ":", -1), locale) {
+		// Drop the codeset and modifier, as in "de_DE.UTF-8@euro".
+		if i := strings.Index(l, "@"); i >= 0 {
+			l = l[0:i]
+		}
+		if i := strings.Index(l, "."); i >= 0 {
+			l = l[0:i]
+		}
+		add(l)
+		if i := strings.Index(l, "_"); i >= 0 {
+			add(l[0:i])
+		}
+	}
+	return langs
+}
+
+// LoadCatalog adds to Messages the translations of the messages of domain
+// into the first of Languages that has a catalog in dir.  Catalogs are
+// looked for where gettext installs them, as dir/LANG/LC_MESSAGES/domain.mo,
+// and as domain.po files in the same place.  The messages of gnuflag itself
+// are in the domain "gnuflag".  It is not an error if there is no catalog.
+func LoadCatalog(dir, domain string) os.Error {
+	for _, lang := range Languages() {
+		for _, ext := range []string{".mo", ".po"} {
+			name := path.Join(dir, lang, "LC_MESSAGES", domain+ext)
+			if _, err := os.Stat(name); err != nil {
+				continue
+			}
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			var m map[string]string
+			if ext == ".mo" {
+				m, err = ReadMO(data)
+			} else {
+				m, err = ReadPO(data)
+			}
+			if err != nil {
+				return os.NewError(name + ": " + err.String())
+			}
+			if Messages == nil {
+				Messages = make(map[string]string)
+			}
+			for id, s := range m {
+				Messages[id] = s
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// ReadMO returns the translations in a catalog in the binary format of
+// gettext's .mo files.  Messages with a context are left out, and only the
+// first plural form of a message is kept.
+func ReadMO(data []byte) (map[string]string, os.Error) {
+	bad := os.NewError("invalid .mo file")
+	if len(data) < 20 {
+		return nil, bad
+	}
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(data) {
+	case 0x950412de:
+		order = binary.LittleEndian
+	case 0xde120495:
+		order = binary.BigEndian
+	default:
+		return nil, bad
+	}
+	n := int(order.Uint32(data[8:]))
+	ids, strs := int(order.Uint32(data[12:])), int(order.Uint32(data[16:]))
+	// str returns the i'th string of the table at offset t.
+	str := func(t, i int) (string, bool) {
+		p := t + 8*i
+		if p < 0 || p+8 > len(data) {
+			return "", false
+		}
+		length, offset := int(order.Uint32(data[p:])), int(order.Uint32(data[p+4:]))
+		if length < 0 || offset < 0 || offset+length > len(data) {
+			return "", false
+		}
+		s := string(data[offset : offset+length])
+		// Plural forms are separated by NULs.
+		if i := strings.Index(s, "\x00"); i >= 0 {
+			s = s[0:i]
+		}
+		return s, true
+	}
+	m := make(map[string]string)
+	for i := 0; i < n; i++ {
+		id, ok := str(ids, i)
+		if !ok {
+			return nil, bad
+		}
+		s, ok := str(strs, i)
+		if !ok {
+			return nil, bad
+		}
+		// Skip the header, which has an empty msgid, and messages with a
+		// context, which is separated from the msgid by an EOT.
+		if id != "" && strings.Index(id, "\x04") < 0 {
+			m[id] = s
+		}
+	}
+	return m, nil
+}
+
+// A poEntry is an entry of a .po file being read.
+type poEntry struct {
+	context, id, str string
+	fuzzy            bool
+	done             bool // the msgstr has been read
+}
+
+// ReadPO returns the translations in a catalog in the text format of
+// gettext's .po files.  As with ReadMO, messages with a context are left out
+// and only the first plural form of a message is kept; so are the fuzzy ones,
+// as gettext's msgfmt leaves them out.
+func ReadPO(data []byte) (map[string]string, os.Error) {
+	m := make(map[string]string)
+	var e poEntry
+	var discard string
+	var cur *string // the string that continuation lines are added to
+	add := func() {
+		if e.id != "" && e.str != "" && e.context == "" && !e.fuzzy {
+			m[e.id] = e.str
+		}
+		e, cur = poEntry{}, nil
+	}
+	for n, line := range strings.Split(string(data), "\n", -1) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' {
+			// Comments precede their entry, so they end the one before.
+			if e.done {
+				add()
+			}
+			if strings.HasPrefix(line, "#,") && strings.Index(line, "fuzzy") >= 0 {
+				e.fuzzy = true
+			}
+			continue
+		}
+		keyword, value := "", line
+		if line[0] != '"' {
+			i := strings.Index(line, " ")
+			if i < 0 {
+				return nil, os.NewError(fmt.Sprintf("line %d: syntax error", n+1))
+			}
+			keyword, value = line[0:i], strings.TrimSpace(line[i+1:])
+		}
+		if (keyword == "msgctxt" || keyword == "msgid") && e.done {
+			add()
+		}
+		switch {
+		case keyword == "":
+			// A continuation line.
+		case keyword == "msgctxt":
+			cur = &e.context
+		case keyword == "msgid":
+			cur = &e.id
+		case keyword == "msgid_plural":
+			cur = &discard
+		case keyword == "msgstr" || keyword == "msgstr[0]":
+			cur, e.done = &e.str, true
+		case strings.HasPrefix(keyword, "msgstr["):
+			cur = &discard
+		default:
+			return nil, os.NewError(fmt.Sprintf("line %d: unknown keyword %s", n+1, keyword))
+		}
+		s, err := strconv.Unquote(value)
+		if err != nil || cur == nil {
+			return nil, os.NewError(fmt.Sprintf("line %d: syntax error", n+1))
+		}
+		*cur += s
+	}
+	if e.done {
+		add()
+	}
+	return m, nil
+}
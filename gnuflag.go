@@ -78,8 +78,12 @@ package gnuflag
 import (
 	"container/vector"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"runtime"
 	"strconv"
+	"strings"
 	"utf8"
 )
 
@@ -237,6 +241,221 @@ func (f *float64Value) set(s string) bool {
 
 func (f *float64Value) String() string { return fmt.Sprintf("%v", *f.p) }
 
+// -- Enum Value
+type enumValue struct {
+	p       *string
+	choices []string
+}
+
+func newEnumValue(val string, choices []string, p *string) *enumValue {
+	*p = val
+	return &enumValue{p, choices}
+}
+
+func (e *enumValue) set(s string) bool {
+	for _, c := range e.choices {
+		if s == c {
+			*e.p = s
+			return true
+		}
+	}
+	return false
+}
+
+func (e *enumValue) String() string { return fmt.Sprintf("%s", *e.p) }
+
+// joinValues joins the n elements of a list value, as returned by at, with
+// commas.
+func joinValues(n int, at func(int) string) string {
+	s := make([]string, n)
+	for i := range s {
+		s[i] = at(i)
+	}
+	return strings.Join(s, ",")
+}
+
+// -- Strings Value
+type stringsValue struct {
+	p *[]string
+}
+
+func newStringsValue(val []string, p *[]string) *stringsValue {
+	*p = val
+	return &stringsValue{p}
+}
+
+func (s *stringsValue) set(val string) bool {
+	*s.p = append(*s.p, val)
+	return true
+}
+
+func (s *stringsValue) String() string { return strings.Join(*s.p, ",") }
+
+// -- Ints Value
+type intsValue struct {
+	p *[]int
+}
+
+func newIntsValue(val []int, p *[]int) *intsValue {
+	*p = val
+	return &intsValue{p}
+}
+
+func (i *intsValue) set(s string) bool {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return false
+	}
+	*i.p = append(*i.p, v)
+	return true
+}
+
+func (i *intsValue) String() string {
+	return joinValues(len(*i.p), func(n int) string { return fmt.Sprintf("%v", (*i.p)[n]) })
+}
+
+// -- Int64s Value
+type int64sValue struct {
+	p *[]int64
+}
+
+func newInt64sValue(val []int64, p *[]int64) *int64sValue {
+	*p = val
+	return &int64sValue{p}
+}
+
+func (i *int64sValue) set(s string) bool {
+	v, err := strconv.Atoi64(s)
+	if err != nil {
+		return false
+	}
+	*i.p = append(*i.p, v)
+	return true
+}
+
+func (i *int64sValue) String() string {
+	return joinValues(len(*i.p), func(n int) string { return fmt.Sprintf("%v", (*i.p)[n]) })
+}
+
+// -- Uints Value
+type uintsValue struct {
+	p *[]uint
+}
+
+func newUintsValue(val []uint, p *[]uint) *uintsValue {
+	*p = val
+	return &uintsValue{p}
+}
+
+func (i *uintsValue) set(s string) bool {
+	v, err := strconv.Atoui(s)
+	if err != nil {
+		return false
+	}
+	*i.p = append(*i.p, v)
+	return true
+}
+
+func (i *uintsValue) String() string {
+	return joinValues(len(*i.p), func(n int) string { return fmt.Sprintf("%v", (*i.p)[n]) })
+}
+
+// -- Uint64s Value
+type uint64sValue struct {
+	p *[]uint64
+}
+
+func newUint64sValue(val []uint64, p *[]uint64) *uint64sValue {
+	*p = val
+	return &uint64sValue{p}
+}
+
+func (i *uint64sValue) set(s string) bool {
+	v, err := strconv.Atoui64(s)
+	if err != nil {
+		return false
+	}
+	*i.p = append(*i.p, v)
+	return true
+}
+
+func (i *uint64sValue) String() string {
+	return joinValues(len(*i.p), func(n int) string { return fmt.Sprintf("%v", (*i.p)[n]) })
+}
+
+// -- Float64s Value
+type float64sValue struct {
+	p *[]float64
+}
+
+func newFloat64sValue(val []float64, p *[]float64) *float64sValue {
+	*p = val
+	return &float64sValue{p}
+}
+
+func (f *float64sValue) set(s string) bool {
+	v, err := strconv.Atof64(s)
+	if err != nil {
+		return false
+	}
+	*f.p = append(*f.p, v)
+	return true
+}
+
+func (f *float64sValue) String() string {
+	return joinValues(len(*f.p), func(n int) string { return fmt.Sprintf("%v", (*f.p)[n]) })
+}
+
+// isList reports whether v is a list value, to which each argument is added.
+func isList(v FlagValue) bool {
+	switch v.(type) {
+	case *stringsValue, *intsValue, *int64sValue, *uintsValue, *uint64sValue, *float64sValue,
+		*prefixesValue, *regexpsValue, *pathsValue:
+		return true
+	}
+	return false
+}
+
+// repeatable reports whether giving f more than once adds to its value each
+// time.
+func repeatable(f *Flag) bool { return isList(f.Value) && flags.policy(f) == Accumulate }
+
+// valueOf returns a FlagValue for the variable p points to, which keeps its
+// current value, or nil if there is none for its type.
+func valueOf(p interface{}) FlagValue {
+	switch p := p.(type) {
+	case *bool:
+		return newBoolValue(*p, p)
+	case *int:
+		return newIntValue(*p, p)
+	case *int64:
+		return newInt64Value(*p, p)
+	case *uint:
+		return newUintValue(*p, p)
+	case *uint64:
+		return newUint64Value(*p, p)
+	case *string:
+		return newStringValue(*p, p)
+	case *float:
+		return newFloatValue(*p, p)
+	case *float64:
+		return newFloat64Value(*p, p)
+	case *[]string:
+		return newStringsValue(*p, p)
+	case *[]int:
+		return newIntsValue(*p, p)
+	case *[]int64:
+		return newInt64sValue(*p, p)
+	case *[]uint:
+		return newUintsValue(*p, p)
+	case *[]uint64:
+		return newUint64sValue(*p, p)
+	case *[]float64:
+		return newFloat64sValue(*p, p)
+	}
+	return nil
+}
+
 // FlagValue is the interface to the dynamic value stored in a flag.
 // (The default value is represented as a string.)
 type FlagValue interface {
@@ -246,33 +465,80 @@ type FlagValue interface {
 
 // A Flag represents the state of a flag.
 type Flag struct {
-	Name      string    // name as it appears on command line
-	ShortName string    // shortname (optional)
-	Usage     string    // help message
-	Value     FlagValue // value as set
-	DefValue  string    // default value (as text); for usage message
+	Name      string       // name as it appears on command line
+	ShortName string       // shortname (optional)
+	Usage     string       // help message
+	Value     FlagValue    // value as set
+	DefValue  string       // default value (as text); for usage message
+	Completer Completer    // completes the flag's argument (optional)
+	Env       string       // environment variable supplying a value (optional)
+	Group     *OptionGroup // option group the flag is listed in (optional)
+	Source    Source       // where the value as set came from
+	Dup       DupPolicy    // what giving the flag more than once does (optional)
+
+	Hidden      bool   // omitted from help output and documentation
+	Deprecated  bool   // warned about when used, and hidden
+	Replacement string // name of the flag to use instead of a deprecated one
+	Persistent  bool   // also accepted by all commands, after their names
+}
+
+// An OptionGroup is a set of flags listed together, under a title, in help
+// output and generated documentation.
+type OptionGroup struct {
+	Title string // e.g. "Compression options:"
+	Order int    // groups are listed by increasing Order, then as defined
 }
 
 type allFlags struct {
 	actual map[string]*Flag
 	formal map[string]*Flag
 	snames map[int]string
+	order  []*Flag // formal flags in order of definition
 	args   *vector.StringVector
+
+	groups []*OptionGroup // option groups in order of definition
+	group  *OptionGroup   // option group of flags being defined
+
+	warned map[string]bool // deprecated flags already warned about
+
+	operands []*operand // declared non-flag arguments
+
+	stop    bool     // stop parsing at the first non-flag argument
+	command *Command // command the flags belong to; nil for the program
+	path    string   // program and command names, e.g. "tool build"
+	offset  int      // index in os.Args of the first argument parsed
+	rest    int      // index in os.Args of the command name parsing stopped at
+
+	dup  DupPolicy            // what giving a flag more than once does, unless the flag says
+	seen map[FlagValue]Source // where each value was set from since the command line was begun
+
+	help, version, printConfig *Flag // the built-in flags, if defined
+
+	dry bool // scanning for completion: set nothing and act on nothing
+}
+
+func newAllFlags() *allFlags {
+	return &allFlags{actual: make(map[string]*Flag), formal: make(map[string]*Flag), snames: make(map[int]string), args: new(vector.StringVector), warned: make(map[string]bool), seen: make(map[FlagValue]Source)}
 }
 
-var flags *allFlags = &allFlags{make(map[string]*Flag), make(map[string]*Flag), make(map[int]string), new([]string)}
+var flags *allFlags = newAllFlags()
 
-// VisitAll visits the flags, calling fn for each. It visits all flags, even those not set.
+// VisitAll visits the flags in the order they were defined, calling fn for each.
+// It visits all flags, even those not set.  The Source of each flag tells
+// where its value came from.
 func VisitAll(fn func(*Flag)) {
-	for _, f := range flags.formal {
+	for _, f := range flags.order {
 		fn(f)
 	}
 }
 
-// Visit visits the flags, calling fn for each. It visits only those flags that have been set.
+// Visit visits the flags in the order they were defined, calling fn for each.
+// It visits only those flags that have been set.
 func Visit(fn func(*Flag)) {
-	for _, f := range flags.actual {
-		fn(f)
+	for _, f := range flags.order {
+		if _, ok := flags.actual[f.Name]; ok {
+			fn(f)
+		}
 	}
 }
 
@@ -292,47 +558,349 @@ func Set(name, value string) bool {
 	if !ok {
 		return false
 	}
-	ok = f.Value.set(value)
+	if flags.apply(f, "--"+name, value, Source{Kind: FromProgram}) != nil {
+		return false
+	}
+	flags.mark(f)
+	return true
+}
+
+// SetEnv binds the named flag to the environment variable env: if the flag is not
+// given on the command line, Parse takes its value from env when that is set and
+// not empty.  The value of a list flag is split at commas, so that APP_TAGS=a,b
+// gives two values.  SetEnv returns false if there is no such flag defined.
+func SetEnv(name, env string) bool {
+	f, ok := flags.formal[name]
 	if !ok {
 		return false
 	}
-	flags.actual[name] = f
+	f.Env = env
 	return true
 }
 
 // Reset prepares gnuflag to parse the arg list again. It is mostly for testing
 // purposes.
 func Reset() {
-	flags = &allFlags{make(map[string]*Flag), make(map[string]*Flag), make(map[int]string), new([]string)}
+	flags = newAllFlags()
+	root = new(Command)
+}
+
+// unquoteUsage extracts a back-quoted name from the usage string of a flag and
+// returns it along with the usage string with the quotes removed.  Given
+// "write output to `FILE`", it returns ("FILE", "write output to FILE").  If
+// there is no back-quoted name, the name is derived from the type of the flag;
+// it is empty for a boolean flag.
+func unquoteUsage(f *Flag) (name string, usage string) {
+	usage = Gettext(f.Usage)
+	if i := strings.Index(usage, "`"); i >= 0 {
+		if j := strings.Index(usage[i+1:], "`"); j >= 0 {
+			name = usage[i+1 : i+1+j]
+			return name, usage[0:i] + name + usage[i+1+j+1:]
+		}
+	}
+	switch v := f.Value.(type) {
+	case *boolValue:
+		name = ""
+	case *intValue, *int64Value, *uintValue, *uint64Value,
+		*intsValue, *int64sValue, *uintsValue, *uint64sValue:
+		name = "N"
+	case *floatValue, *float64Value, *float64sValue:
+		name = "NUM"
+	case *sizeValue:
+		name = "SIZE"
+	case *ipValue:
+		name = "ADDR"
+	case *prefixValue, *prefixesValue:
+		name = "PREFIX"
+	case *addrValue:
+		name = "HOST:PORT"
+	case *hardwareAddrValue:
+		name = "MAC"
+	case *urlValue:
+		name = "URL"
+	case *timeValue:
+		name = "TIME"
+	case *regexpValue, *regexpsValue:
+		name = "PATTERN"
+	case *pathValue:
+		name = v.opts.metavar()
+	case *pathsValue:
+		name = v.opts.metavar()
+	case *enumValue:
+		name = "{" + strings.Join(v.choices, "|") + "}"
+	default:
+		name = "STRING"
+	}
+	return
+}
+
+// typeName returns the name of the type of value a flag holds.
+func typeName(f *Flag) string {
+	switch f.Value.(type) {
+	case *boolValue:
+		return "bool"
+	case *intValue:
+		return "int"
+	case *int64Value:
+		return "int64"
+	case *uintValue:
+		return "uint"
+	case *uint64Value:
+		return "uint64"
+	case *stringValue:
+		return "string"
+	case *floatValue:
+		return "float"
+	case *float64Value:
+		return "float64"
+	case *enumValue:
+		return "enum"
+	case *sizeValue:
+		return "size"
+	case *ipValue:
+		return "ip"
+	case *prefixValue:
+		return "prefix"
+	case *prefixesValue:
+		return "[]prefix"
+	case *addrValue:
+		return "host:port"
+	case *hardwareAddrValue:
+		return "mac"
+	case *urlValue:
+		return "url"
+	case *timeValue:
+		return "time"
+	case *regexpValue:
+		return "regexp"
+	case *regexpsValue:
+		return "[]regexp"
+	case *pathValue:
+		return "path"
+	case *pathsValue:
+		return "[]path"
+	case *stringsValue:
+		return "[]string"
+	case *intsValue:
+		return "[]int"
+	case *int64sValue:
+		return "[]int64"
+	case *uintsValue:
+		return "[]uint"
+	case *uint64sValue:
+		return "[]uint64"
+	case *float64sValue:
+		return "[]float64"
+	}
+	return "value"
+}
+
+// progName returns the name the program was invoked as, without directories.
+func progName() string {
+	if len(os.Args) == 0 {
+		return ""
+	}
+	return path.Base(os.Args[0])
+}
+
+// Output is where PrintDefaults and the default Usage write.
+var Output io.Writer = os.Stderr
+
+// helpColumn is the column at which PrintDefaults starts the descriptions of
+// the flags, as argp does.
+const helpColumn = 29
+
+// helpWidth returns the width of the terminal, which is taken from $COLUMNS
+// and is 80 if that is not set.
+func helpWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+// wrap breaks s into lines of at most width characters.  Words longer than
+// width get a line of their own.
+func wrap(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// isZeroDefault reports whether the default value of f is the zero value of its
+// type, which is not worth mentioning in help output.
+func isZeroDefault(f *Flag) bool {
+	switch f.DefValue {
+	case "", "0", "false":
+		return true
+	}
+	return false
 }
 
-// PrintDefaults prints to standard error the default values of all defined flags.
+// defaultString returns the default value of f as shown in help output and
+// documentation.
+func defaultString(f *Flag) string {
+	if _, ok := f.Value.(*stringValue); ok {
+		// put quotes on the value
+		return fmt.Sprintf("%q", f.DefValue)
+	}
+	return f.DefValue
+}
+
+// PrintDefaults prints to Output the defined flags in the style of GNU --help
+// output: the options in the order they were defined, with their descriptions
+// aligned in a second column and wrapped to the width of the terminal.  Flags
+// in option groups are listed after the others, under the group titles.
 func PrintDefaults() {
-	VisitAll(func(f *Flag) {
-		var format string
-		if _, ok := f.Value.(*stringValue); ok {
-			// put quotes on the value
-			format = "--%s=%q: %s\n"
-		} else {
-			format = "--%s=%s: %s\n"
+	width := helpWidth() - 1
+	if width-helpColumn < 20 {
+		width = helpColumn + 20
+	}
+	visitGroups(func(g *OptionGroup, group []*Flag) {
+		if g != nil {
+			fmt.Fprintf(Output, "\n %s\n", Gettext(g.Title))
 		}
-		if f.ShortName != "" {
-			fmt.Fprintf(os.Stderr, "  -%s, "+format, f.ShortName, f.Name, f.DefValue, f.Usage)
-		} else {
-			fmt.Fprintf(os.Stderr, "      "+format, f.Name, f.DefValue, f.Usage)
+		for _, f := range group {
+			printDefault(f, width)
 		}
 	})
 }
 
+// printDefault prints the help output for f, wrapped to width.
+func printDefault(f *Flag, width int) {
+	arg, usage := unquoteUsage(f)
+	opt := "      --" + f.Name
+	if f.ShortName != "" {
+		opt = "  -" + f.ShortName + ", --" + f.Name
+	}
+	if arg != "" {
+		opt += "=" + arg
+	}
+	if !isZeroDefault(f) {
+		usage += " " + fmt.Sprintf(Gettext("(default: %s)"), defaultString(f))
+	}
+	lines := wrap(usage, width-helpColumn)
+	// Long options overflow onto a line of their own.
+	if n := utf8.RuneCountInString(opt); len(lines) == 0 || n > helpColumn-2 {
+		fmt.Fprintln(Output, opt)
+	} else {
+		fmt.Fprint(Output, opt+strings.Repeat(" ", helpColumn-n))
+		fmt.Fprintln(Output, lines[0])
+		lines = lines[1:]
+	}
+	for _, l := range lines {
+		fmt.Fprintln(Output, strings.Repeat(" ", helpColumn)+l)
+	}
+}
+
 // UsageTemplate is a string formatting template that can be overridden to provide
-// more useful usage messages. The %s argument is the program name.
-var UsageTemplate = "Usage: %s [OPTION]... [ARGS]\n"
+// more useful usage messages. The %s argument is the program name.  If operands
+// have been declared, the default template is replaced by a synopsis of them.
+var UsageTemplate = defaultUsageTemplate
+
+const defaultUsageTemplate = "Usage: %s [OPTION]... [ARGS]\n"
 
-// Usage prints to standard error a default usage message documenting all defined flags.
+// Usage prints to Output a default usage message documenting all defined flags.
 // The function is a variable that may be changed to point to a custom function.
 var Usage = func() {
-	fmt.Fprintf(os.Stderr, UsageTemplate, os.Args[0])
+	c := flags.command
+	if c == nil {
+		if len(flags.operands) > 0 && UsageTemplate == defaultUsageTemplate {
+			fmt.Fprintf(Output, Gettext("Usage: %s [OPTION]... %s\n"), os.Args[0], flags.synopsis())
+		} else {
+			fmt.Fprintf(Output, Gettext(UsageTemplate), os.Args[0])
+		}
+		c = root
+	} else {
+		synopsis := c.Synopsis
+		if synopsis == "" {
+			synopsis = flags.synopsis()
+		}
+		usage := fmt.Sprintf(Gettext("Usage: %s [OPTION]... %s\n"), flags.path, synopsis)
+		fmt.Fprintln(Output, strings.TrimSpace(usage))
+		if c.Summary != "" {
+			fmt.Fprintln(Output, Gettext(c.Summary))
+		}
+	}
 	PrintDefaults()
+	c.printCommands()
+}
+
+// Copyright is printed by --version after the version information.  It is
+// meant for the copyright and license notices the GNU coding standards ask
+// for.
+var Copyright = ""
+
+// BuildInfo is printed by --version after the version number.  It is meant
+// for version control and build metadata, such as the revision the program
+// was built from, which the program's build may fill in.
+var BuildInfo = ""
+
+// version is the version printed by --version.
+var version string
+
+// DefineHelp defines the standard --help flag, which makes Parse print the
+// usage message to standard output and exit successfully.  The flag is
+// persistent, so that "tool COMMAND --help" describes COMMAND.
+func DefineHelp() {
+	Bool("help", "", false, "display this help and exit")
+	flags.help = flags.formal["help"]
+	flags.help.Persistent = true
+}
+
+// DefineVersion defines the standard --version flag, which makes Parse print
+// the program name and v, followed by BuildInfo and Copyright, to standard
+// output and exit successfully.
+func DefineVersion(v string) {
+	version = v
+	Bool("version", "", false, "output version information and exit")
+	flags.version = flags.formal["version"]
+}
+
+// PrintVersion prints to w the version information printed by --version.
+func PrintVersion(w io.Writer) {
+	fmt.Fprintf(w, "%s %s\n", progName(), version)
+	if BuildInfo != "" {
+		fmt.Fprintln(w, BuildInfo)
+	}
+	fmt.Fprintf(w, Gettext("Built with %s for %s/%s.\n"), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if Copyright != "" {
+		fmt.Fprintln(w)
+		fmt.Fprint(w, Copyright)
+		if !strings.HasSuffix(Copyright, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// builtin performs the action of flag if it is --help or --version and has
+// been set.
+func (f *allFlags) builtin(flag *Flag) {
+	if f.dry || flag.Value.String() != "true" {
+		return
+	}
+	switch flag {
+	case f.help:
+		Output = os.Stdout
+		Usage()
+		os.Exit(0)
+	case f.version:
+		PrintVersion(os.Stdout)
+		os.Exit(0)
+	}
 }
 
 // NFlag is the number of actual flags processed.
@@ -353,9 +921,9 @@ func NArg() int { return flags.args.Len() }
 // Args returns the non-flag command-line arguments.
 func Args() []string { return flags.args.Data() }
 
-func add(name string, shortName string, value FlagValue, usage string) {
+func add(name string, shortName string, value FlagValue, usage string) *Flag {
 	// Remember the default value as a string; it won't change.
-	f := &Flag{name, shortName, usage, value, value.String()}
+	f := &Flag{Name: name, ShortName: shortName, Usage: usage, Value: value, DefValue: value.String()}
 	_, alreadythere := flags.formal[name]
 	if alreadythere {
 		fmt.Fprintln(os.Stderr, "flag redefined:", name)
@@ -372,7 +940,120 @@ func add(name string, shortName string, value FlagValue, usage string) {
 	}
 	flags.snames[r] = name
 noShortName:
+	f.Group = flags.group
 	flags.formal[name] = f
+	flags.order = append(flags.order, f)
+	return f
+}
+
+// MarkPersistent makes the named flag persistent: the commands of the program,
+// or of the command being defined, and all their descendants accept it too.
+// It returns false if there is no such flag defined.
+func MarkPersistent(name string) bool {
+	f, ok := flags.formal[name]
+	if !ok {
+		return false
+	}
+	f.Persistent = true
+	return true
+}
+
+// MarkHidden hides the named flag: it can still be given on the command line,
+// but is left out of help output, documentation and completions.  MarkHidden
+// returns false if there is no such flag defined.
+func MarkHidden(name string) bool {
+	f, ok := flags.formal[name]
+	if !ok {
+		return false
+	}
+	f.Hidden = true
+	return true
+}
+
+// MarkDeprecated deprecates the named flag: it is hidden, and Parse warns once
+// when it is used that the flag named replacement should be used instead.
+// The replacement may be empty.  MarkDeprecated returns false if there is no
+// such flag defined.
+func MarkDeprecated(name, replacement string) bool {
+	f, ok := flags.formal[name]
+	if !ok {
+		return false
+	}
+	f.Deprecated = true
+	f.Replacement = replacement
+	return true
+}
+
+// DeprecatedAlias defines a deprecated flag with specified name and short name
+// that forwards its value to the already defined flag named replacement: giving
+// either flag sets the same value.
+func DeprecatedAlias(name, shortName, replacement string) {
+	r, ok := flags.formal[replacement]
+	if !ok {
+		fmt.Fprintln(os.Stderr, "flag alias for undefined flag:", name)
+		panic("flag alias for undefined flag")
+	}
+	f := add(name, shortName, r.Value, r.Usage)
+	f.DefValue = r.DefValue
+	f.Deprecated = true
+	f.Replacement = replacement
+}
+
+// listed reports whether f appears in help output and documentation.
+func listed(f *Flag) bool { return !f.Hidden && !f.Deprecated }
+
+// warnDeprecated warns, once, that flag is deprecated.
+func (f *allFlags) warnDeprecated(flag *Flag) {
+	if f.dry || !flag.Deprecated || f.warned[flag.Name] {
+		return
+	}
+	f.warned[flag.Name] = true
+	if flag.Replacement != "" {
+		fmt.Fprintf(os.Stderr, Gettext("--%s is deprecated; use --%s\n"), flag.Name, flag.Replacement)
+	} else {
+		fmt.Fprintf(os.Stderr, Gettext("--%s is deprecated\n"), flag.Name)
+	}
+}
+
+// Group starts an option group with the given title and order.  The flags
+// defined after it belong to the group, until the next call to Group or
+// EndGroup.
+func Group(title string, order int) *OptionGroup {
+	g := &OptionGroup{title, order}
+	flags.groups = append(flags.groups, g)
+	flags.group = g
+	return g
+}
+
+// EndGroup ends the current option group: flags defined after it belong to no
+// group, and are listed before all groups.
+func EndGroup() { flags.group = nil }
+
+// visitGroups calls fn with the listed flags of each option group that has
+// any, in the order the groups are listed.  The flags in no group come first,
+// with a nil group.
+func visitGroups(fn func(g *OptionGroup, group []*Flag)) {
+	groups := []*OptionGroup{nil}
+	for _, g := range flags.groups {
+		// Insertion sort, which keeps groups of equal order as defined.
+		i := len(groups)
+		groups = append(groups, g)
+		for ; i > 1 && groups[i-1].Order > g.Order; i-- {
+			groups[i] = groups[i-1]
+		}
+		groups[i] = g
+	}
+	for _, g := range groups {
+		var group []*Flag
+		for _, f := range flags.order {
+			if f.Group == g && listed(f) {
+				group = append(group, f)
+			}
+		}
+		if len(group) > 0 {
+			fn(g, group)
+		}
+	}
 }
 
 // BoolVar defines a bool flag with specified name, short name, default value, and
@@ -460,6 +1141,22 @@ func String(name, shortName, value string, usage string) *string {
 	return p
 }
 
+// EnumVar defines a string flag with specified name, default value, and usage string
+// that only accepts one of the given choices. The argument p points to a string
+// variable in which to store the value of the flag.
+func EnumVar(p *string, name, shortName string, choices []string, value string, usage string) {
+	add(name, shortName, newEnumValue(value, choices, p), usage)
+}
+
+// Enum defines a string flag with specified name, default value, and usage string
+// that only accepts one of the given choices. The return value is the address of a
+// string variable that stores the value of the flag.
+func Enum(name, shortName string, choices []string, value string, usage string) *string {
+	p := new(string)
+	EnumVar(p, name, shortName, choices, value, usage)
+	return p
+}
+
 // FloatVar defines a float flag with specified name, default value, and usage string.
 // The argument p points to a float variable in which to store the value of the flag.
 func FloatVar(p *float, name, shortName string, value float, usage string) {
@@ -488,48 +1185,156 @@ func Float64(name, shortName string, value float64, usage string) *float64 {
 	return p
 }
 
+// StringsVar defines a repeatable string flag with specified name, default value, and usage
+// string.  The argument p points to a []string variable to which each argument of the
+// flag is appended.
+func StringsVar(p *[]string, name, shortName string, value []string, usage string) {
+	add(name, shortName, newStringsValue(value, p), usage)
+}
+
+// Strings defines a repeatable string flag with specified name, default value, and usage
+// string.  The return value is the address of a []string variable to which each
+// argument of the flag is appended.
+func Strings(name, shortName string, value []string, usage string) *[]string {
+	p := new([]string)
+	StringsVar(p, name, shortName, value, usage)
+	return p
+}
+
+// IntsVar defines a repeatable int flag with specified name, default value, and usage
+// string.  The argument p points to a []int variable to which each argument of the
+// flag is appended.
+func IntsVar(p *[]int, name, shortName string, value []int, usage string) {
+	add(name, shortName, newIntsValue(value, p), usage)
+}
+
+// Ints defines a repeatable int flag with specified name, default value, and usage
+// string.  The return value is the address of a []int variable to which each
+// argument of the flag is appended.
+func Ints(name, shortName string, value []int, usage string) *[]int {
+	p := new([]int)
+	IntsVar(p, name, shortName, value, usage)
+	return p
+}
 
-func (f *allFlags) parseOne(index int) (ok bool, next int) {
-	s := os.Args[index]
+// Int64sVar defines a repeatable int64 flag with specified name, default value, and usage
+// string.  The argument p points to a []int64 variable to which each argument of the
+// flag is appended.
+func Int64sVar(p *[]int64, name, shortName string, value []int64, usage string) {
+	add(name, shortName, newInt64sValue(value, p), usage)
+}
+
+// Int64s defines a repeatable int64 flag with specified name, default value, and usage
+// string.  The return value is the address of a []int64 variable to which each
+// argument of the flag is appended.
+func Int64s(name, shortName string, value []int64, usage string) *[]int64 {
+	p := new([]int64)
+	Int64sVar(p, name, shortName, value, usage)
+	return p
+}
+
+// UintsVar defines a repeatable uint flag with specified name, default value, and usage
+// string.  The argument p points to a []uint variable to which each argument of the
+// flag is appended.
+func UintsVar(p *[]uint, name, shortName string, value []uint, usage string) {
+	add(name, shortName, newUintsValue(value, p), usage)
+}
+
+// Uints defines a repeatable uint flag with specified name, default value, and usage
+// string.  The return value is the address of a []uint variable to which each
+// argument of the flag is appended.
+func Uints(name, shortName string, value []uint, usage string) *[]uint {
+	p := new([]uint)
+	UintsVar(p, name, shortName, value, usage)
+	return p
+}
+
+// Uint64sVar defines a repeatable uint64 flag with specified name, default value, and usage
+// string.  The argument p points to a []uint64 variable to which each argument of the
+// flag is appended.
+func Uint64sVar(p *[]uint64, name, shortName string, value []uint64, usage string) {
+	add(name, shortName, newUint64sValue(value, p), usage)
+}
+
+// Uint64s defines a repeatable uint64 flag with specified name, default value, and usage
+// string.  The return value is the address of a []uint64 variable to which each
+// argument of the flag is appended.
+func Uint64s(name, shortName string, value []uint64, usage string) *[]uint64 {
+	p := new([]uint64)
+	Uint64sVar(p, name, shortName, value, usage)
+	return p
+}
+
+// Float64sVar defines a repeatable float64 flag with specified name, default value, and usage
+// string.  The argument p points to a []float64 variable to which each argument of the
+// flag is appended.
+func Float64sVar(p *[]float64, name, shortName string, value []float64, usage string) {
+	add(name, shortName, newFloat64sValue(value, p), usage)
+}
+
+// Float64s defines a repeatable float64 flag with specified name, default value, and usage
+// string.  The return value is the address of a []float64 variable to which each
+// argument of the flag is appended.
+func Float64s(name, shortName string, value []float64, usage string) *[]float64 {
+	p := new([]float64)
+	Float64sVar(p, name, shortName, value, usage)
+	return p
+}
+
+func (f *allFlags) parseOne(args []string, index int) (ok bool, next int, err *Error) {
+	s := args[index]
 	// Take care of non-flag arguments.
 	if len(s) == 0 || s[0] != '-' || s == "-" {
+		if f.stop {
+			// The rest are a command and its arguments.
+			f.rest = f.offset + index
+			v := vector.StringVector(args[index:])
+			f.args.AppendVector(&v)
+			return false, -1, nil
+		}
 		f.args.Push(s)
-		return true, index + 1
+		return true, index + 1, nil
 	}
 	if s == "--" {
-		v := vector.StringVector(os.Args[index+1:])
+		v := vector.StringVector(args[index+1:])
 		f.args.AppendVector(&v)
-		return false, -1
+		return false, -1, nil
 	}
-	var errorStr string
 	// Sort out flag arguments.
+	source := Source{Kind: FromArgs, Index: f.offset + index}
 	if s[1] != '-' {
+		arg := s
 		for {
 			// Deal with shortname flags
 			sname, sz := utf8.DecodeRuneInString(s[1:])
 			if sname == utf8.RuneError {
-				errorStr = "invalid UTF-8 character"
+				err = &Error{Kind: BadSyntax, Option: arg, Msg: Gettext("invalid UTF-8 character")}
 				goto argError
 			}
+			option := "-" + string(sname)
 			name, ok := f.snames[sname]
 			if !ok {
-				errorStr = fmt.Sprintf("flag provided but not defined: -%s\n", string(sname))
+				err = &Error{Kind: UnknownOption, Option: option, Msg: fmt.Sprintf(Gettext("flag provided but not defined: %s"), option),
+					Suggestions: f.suggestShort(sname, arg[1:])}
 				goto argError
 			}
 			rest := s[1+sz:]
-			// Check for (bad) extraneous flags
-			if _, ok := f.actual[name]; ok {
-				errorStr = fmt.Sprintf("flag specified twice: -%s\n", string(sname))
-				goto argError
-			}
 			flag, ok := f.formal[name]
 			if !ok {
-				errorStr = fmt.Sprintf("flag provided but not defined: -%s\n", string(sname))
+				err = &Error{Kind: UnknownOption, Option: option, Msg: fmt.Sprintf(Gettext("flag provided but not defined: %s"), option)}
 				goto argError
 			}
+			f.warnDeprecated(flag)
 			// Try and understand the value of the flag
-			if f, ok := flag.Value.(*boolValue); ok { // special case: doesn't need an arg
-				f.set("true")
+			if _, ok := flag.Value.(*boolValue); ok { // special case: doesn't need an arg
+				if err = f.apply(flag, option, "true", source); err != nil {
+					goto argError
+				}
+				f.mark(flag)
+				f.builtin(flag)
+				if rest == "" {
+					break
+				}
 				s = "-" + rest
 				continue
 			}
@@ -537,26 +1342,26 @@ func (f *allFlags) parseOne(index int) (ok bool, next int) {
 			if rest != "" {
 				has_value = true
 			}
-			if !has_value && index < len(os.Args)-1 {
+			if !has_value && index < len(args)-1 {
 				has_value = true
 				index++
-				rest = os.Args[index]
+				rest = args[index]
 			}
 			if !has_value {
-				errorStr = fmt.Sprintf("flag needs an argument: -%s\n", string(sname))
+				err = &Error{Kind: MissingArgument, Option: option, Msg: fmt.Sprintf(Gettext("flag needs an argument: %s"), option)}
 				goto argError
 			}
-			if ok = flag.Value.set(rest); !ok {
-				errorStr = fmt.Sprintf("invalid value %s for flag: -%s\n", rest, string(sname))
+			if err = f.apply(flag, option, rest, source); err != nil {
 				goto argError
 			}
+			f.mark(flag)
 			break
 		}
 	} else {
 		// Long name flags
 		name := s[2:]
 		if name[0] == '-' || name[0] == '=' {
-			errorStr = fmt.Sprintln("bad flag syntax:", s)
+			err = &Error{Kind: BadSyntax, Option: s, Msg: fmt.Sprintf(Gettext("bad flag syntax: %s"), s)}
 			goto argError
 		}
 		has_value := false
@@ -569,60 +1374,152 @@ func (f *allFlags) parseOne(index int) (ok bool, next int) {
 				break
 			}
 		}
-		// Check for (bad) extraneous flags
-		if _, ok := f.actual[name]; ok {
-			errorStr = fmt.Sprintf("flag specified twice: -%s\n", name)
-			goto argError
-		}
+		option := "--" + name
 		flag, ok := f.formal[name]
 		if !ok {
-			errorStr = fmt.Sprintf("flag provided but not defined: -%s\n", name)
+			err = &Error{Kind: UnknownOption, Option: option, Msg: fmt.Sprintf(Gettext("unrecognized option '%s'"), option),
+				Suggestions: f.suggest(name)}
 			goto argError
 		}
+		f.warnDeprecated(flag)
 		// Try and understand the value of the flag
-		if f, ok := flag.Value.(*boolValue); ok { // special case: doesn't need an arg
-			if has_value {
-				if !f.set(value) {
-					errorStr = fmt.Sprintf("invalid boolean value %t for flag: -%s\n", value, name)
-					goto argError
-				}
-			} else {
-				f.set("true")
+		if _, ok := flag.Value.(*boolValue); ok { // special case: doesn't need an arg
+			if has_value && GNUDiagnostics {
+				// getopt doesn't take values for options without arguments.
+				err = &Error{Kind: UnexpectedArgument, Option: option, Value: value,
+					Msg: fmt.Sprintf(Gettext("flag takes no argument: %s"), option)}
+				goto argError
 			}
+			if !has_value {
+				value = "true"
+			}
+			if err = f.apply(flag, option, value, source); err != nil {
+				goto argError
+			}
+			f.mark(flag)
+			f.builtin(flag)
 		} else {
 			// It must have a value, which might be the next argument.
-			if !has_value && index < len(os.Args)-1 {
+			if !has_value && index < len(args)-1 {
 				// value is the next arg
 				has_value = true
 				index++
-				value = os.Args[index]
+				value = args[index]
 			}
 			if !has_value {
-				errorStr = fmt.Sprintf("flag needs an argument: -%s\n", name)
+				err = &Error{Kind: MissingArgument, Option: option, Msg: fmt.Sprintf(Gettext("flag needs an argument: %s"), option)}
 				goto argError
 			}
-			if ok = flag.Value.set(value); !ok {
-				errorStr = fmt.Sprintf("invalid value %s for flag: -%s\n", value, name)
+			if err = f.apply(flag, option, value, source); err != nil {
 				goto argError
 			}
+			f.mark(flag)
 		}
-		f.actual[name] = flag
 	}
-	return true, index + 1
+	return true, index + 1, nil
 argError:
-	fmt.Fprint(os.Stderr, errorStr)
-	Usage()
-	os.Exit(2)
-	return false, -1
+	return false, -1, err
+}
+
+// parseEnv sets the flags bound to environment variables from the environment.
+// It runs before the command line is parsed, so that the command line takes
+// precedence.
+func (f *allFlags) parseEnv() *Error {
+	for _, flag := range f.order {
+		// A persistent flag may have been set before the command name.
+		if _, ok := f.seen[flag.Value]; flag.Env == "" || ok {
+			continue
+		}
+		value := os.Getenv(flag.Env)
+		if value == "" {
+			continue
+		}
+		values := []string{value}
+		if isList(flag.Value) {
+			values = strings.Split(value, ",", -1)
+		}
+		ok := f.apply(flag, "", values[0], Source{Kind: FromEnv, Name: flag.Env}) == nil
+		for _, v := range values[1:] {
+			ok = ok && flag.Value.set(v)
+		}
+		if !ok {
+			return &Error{Kind: InvalidValue, Value: value,
+				Msg: fmt.Sprintf(Gettext("invalid value %s for environment variable %s"), value, flag.Env)}
+		}
+	}
+	return nil
 }
 
 // Parse parses the command-line flags.  Must be called after all flags are defined
-// and before any are accessed by the program.
+// and before any are accessed by the program.  If the first argument is
+// CompletionArg, Parse prints completions for the rest of the command line
+// instead and exits.  If commands have been added, Parse stops at the first
+// non-flag argument, which names the command for Dispatch.  If the command
+// line is invalid, Parse prints the error and the usage message and exits.
 func Parse() {
-	var ok bool
-	for i := 1; i < len(os.Args); {
-		if ok, i = flags.parseOne(i); !ok {
+	if len(os.Args) > 1 && os.Args[1] == CompletionArg {
+		for _, c := range Complete(os.Args[2:]) {
+			fmt.Println(c)
+		}
+		os.Exit(0)
+	}
+	if err := ParseArgs(os.Args[1:]); err != nil {
+		fail(err)
+	}
+}
+
+// ParseArgs parses args, a command line without the program name, as Parse
+// parses that of the program, but returns an invalid command line as an
+// *Error rather than printing it and exiting.  The builtin --help and
+// --version flags still exit.
+func ParseArgs(args []string) os.Error {
+	flags.seen = make(map[FlagValue]Source)
+	flags.stop = root.dispatches()
+	flags.offset = 1
+	return flags.parse(args)
+}
+
+// fail prints err and the usage message, unless Opterr is false, and exits.
+func fail(err os.Error) {
+	switch {
+	case !Opterr:
+		break
+	case GNUDiagnostics:
+		fmt.Fprintf(os.Stderr, "%s: %s\n", progName(), err)
+		if flags.help != nil {
+			fmt.Fprintf(os.Stderr, Gettext("Try '%s --help' for more information.\n"), commandPath())
+		}
+	default:
+		fmt.Fprintln(os.Stderr, err)
+		Usage()
+	}
+	os.Exit(2)
+}
+
+// parse parses args, a command line without the program name.
+func (f *allFlags) parse(args []string) os.Error {
+	if err := f.parseEnv(); err != nil {
+		return err
+	}
+	for i := 0; i < len(args); {
+		ok, next, err := f.parseOne(args, i)
+		if err != nil {
+			return err
+		}
+		if !ok {
 			break
 		}
+		i = next
+	}
+	if f.printConfig != nil && f.printConfig.Value.String() == "true" {
+		PrintConfig(os.Stdout)
+		os.Exit(0)
+	}
+	if f.stop {
+		return nil // the arguments belong to a command
+	}
+	if err := f.parseOperands(f.args.Data()); err != nil {
+		return err
 	}
+	return nil
 }
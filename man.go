@@ -0,0 +1,163 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// A Program describes a program in generated documentation, such as a manual
+// page.  Everything but the options comes from here; the options are taken
+// from the defined flags.
+type Program struct {
+	Name        string  // program name; the base name of os.Args[0] if empty
+	Section     string  // manual section; "1" if empty
+	Version     string  // version, e.g. "1.2"
+	Date        string  // date of the documentation; the current month if empty
+	Summary     string  // one-line description, for the NAME section
+	Synopsis    string  // what follows the options; the operands if empty
+	Description string  // paragraphs separated by blank lines
+	Environment []Entry // environment variables the program uses
+	Files       []Entry // files the program uses
+}
+
+// An Entry is a named item in a list such as a Program's environment variables
+// or files.
+type Entry struct {
+	Name        string
+	Description string
+}
+
+func (p *Program) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return progName()
+}
+
+// synopsis returns what follows the options in the synopsis of p.
+func (p *Program) synopsis() string {
+	if p.Synopsis != "" {
+		return p.Synopsis
+	}
+	return flags.synopsis()
+}
+
+// environment returns the environment variables bound to listed flags followed
+// by those listed in p.
+func (p *Program) environment() []Entry {
+	var env []Entry
+	for _, f := range flags.order {
+		if f.Env != "" && listed(f) {
+			env = append(env, Entry{f.Env, "Used as the value of --" + f.Name + " when that is not given."})
+		}
+	}
+	return append(env, p.Environment...)
+}
+
+// ManPage writes to w a manual page for the program p in troff format, with the
+// defined flags as its OPTIONS section.
+func ManPage(w io.Writer, p *Program) {
+	name := p.name()
+	section := p.Section
+	if section == "" {
+		section = "1"
+	}
+	date := p.Date
+	if date == "" {
+		date = time.LocalTime().Format("January 2006")
+	}
+	fmt.Fprintf(w, ".TH %s %s \"%s\" \"%s\" \"User Commands\"\n",
+		troffEscape(strings.ToUpper(name)), section, troffEscape(date),
+		troffEscape(strings.TrimSpace(name+" "+p.Version)))
+	fmt.Fprintf(w, ".SH NAME\n%s", troffEscape(name))
+	if p.Summary != "" {
+		fmt.Fprintf(w, " \\- %s", troffEscape(p.Summary))
+	}
+	fmt.Fprintf(w, "\n.SH SYNOPSIS\n.B %s\n[\\fIOPTION\\fR]...", troffEscape(name))
+	if synopsis := p.synopsis(); synopsis != "" {
+		fmt.Fprintf(w, " \\fI%s\\fR", troffEscape(synopsis))
+	}
+	fmt.Fprintf(w, "\n")
+	if p.Description != "" {
+		fmt.Fprintf(w, ".SH DESCRIPTION\n")
+		for i, para := range strings.Split(strings.TrimSpace(p.Description), "\n\n", -1) {
+			if i > 0 {
+				fmt.Fprintf(w, ".PP\n")
+			}
+			fmt.Fprintf(w, "%s\n", troffEscape(para))
+		}
+	}
+	if len(flags.order) > 0 {
+		fmt.Fprintf(w, ".SH OPTIONS\n")
+		visitGroups(func(g *OptionGroup, group []*Flag) {
+			if g != nil {
+				fmt.Fprintf(w, ".SS %s\n", troffEscape(g.Title))
+			}
+			for _, f := range group {
+				manOption(w, f)
+			}
+		})
+	}
+	manEntries(w, "ENVIRONMENT", p.environment())
+	manEntries(w, "FILES", p.Files)
+}
+
+// manOption writes the tagged paragraph documenting f.
+func manOption(w io.Writer, f *Flag) {
+	arg, usage := unquoteUsage(f)
+	fmt.Fprintf(w, ".TP\n")
+	if f.ShortName != "" {
+		fmt.Fprintf(w, "\\fB\\-%s\\fR, ", troffEscape(f.ShortName))
+	}
+	fmt.Fprintf(w, "\\fB\\-\\-%s\\fR", troffEscape(f.Name))
+	if arg != "" {
+		fmt.Fprintf(w, "=\\fI%s\\fR", troffEscape(arg))
+	}
+	fmt.Fprintf(w, "\n%s\n", troffEscape(usage))
+}
+
+// manEntries writes a section of tagged paragraphs, one for each entry.
+func manEntries(w io.Writer, section string, entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(w, ".SH %s\n", section)
+	for _, e := range entries {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", troffEscape(e.Name), troffEscape(e.Description))
+	}
+}
+
+// troffEscape escapes the characters of s that troff would otherwise
+// interpret: backslashes, hyphens (which would become typographic hyphens
+// rather than minus signs) and control characters at the start of a line.
+func troffEscape(s string) string {
+	s = strings.Replace(s, "\\", "\\e", -1)
+	s = strings.Replace(s, "-", "\\-", -1)
+	lines := strings.Split(s, "\n", -1)
+	for i, l := range lines {
+		if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+			lines[i] = "\\&" + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
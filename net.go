@@ -0,0 +1,384 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// An IPPrefix is an IP network given in CIDR notation, e.g. "10.0.0.0/8".
+type IPPrefix struct {
+	IP   net.IP // the network address, with the host bits cleared
+	Bits int    // the length of the prefix
+}
+
+// String returns p in CIDR notation.
+func (p IPPrefix) String() string {
+	if p.IP == nil {
+		return ""
+	}
+	return p.IP.String() + "/" + strconv.Itoa(p.Bits)
+}
+
+// Contains reports whether ip is in the network p.
+func (p IPPrefix) Contains(ip net.IP) bool {
+	if len(p.IP) == net.IPv4len {
+		ip = ip.To4()
+	}
+	if ip == nil || len(ip) != len(p.IP) {
+		return false
+	}
+	return maskIP(ip, p.Bits).String() == p.IP.String()
+}
+
+// maskIP returns ip with all but the first bits bits cleared.
+func maskIP(ip net.IP, bits int) net.IP {
+	m := make(net.IP, len(ip))
+	for i := range ip {
+		switch {
+		case bits >= 8*(i+1):
+			m[i] = ip[i]
+		case bits > 8*i:
+			m[i] = ip[i] &^ (0xff >> uint(bits-8*i))
+		}
+	}
+	return m
+}
+
+// parsePrefix parses a network in CIDR notation.
+func parsePrefix(s string) (IPPrefix, bool) {
+	i := strings.Index(s, "/")
+	if i < 0 {
+		return IPPrefix{}, false
+	}
+	ip := parseIP(s[0:i])
+	bits, err := strconv.Atoi(s[i+1:])
+	if ip == nil || err != nil || bits < 0 || bits > 8*len(ip) {
+		return IPPrefix{}, false
+	}
+	return IPPrefix{maskIP(ip, bits), bits}, true
+}
+
+// parseIP parses an IP address, which it returns in 4-byte form if it is an
+// IPv4 address.
+func parseIP(s string) net.IP {
+	ip := net.ParseIP(s)
+	if ip4 := ip.To4(); ip4 != nil && strings.Index(s, ":") < 0 {
+		return ip4
+	}
+	return ip
+}
+
+// An Addr is a network address given as host:port, e.g. "example.com:80",
+// "10.0.0.1:8080" or "[::1]:8080".  An empty host means all local addresses.
+type Addr struct {
+	Host string
+	Port int
+}
+
+// String returns a as host:port, with brackets around an IPv6 host.
+func (a Addr) String() string {
+	if strings.Index(a.Host, ":") >= 0 {
+		return "[" + a.Host + "]:" + strconv.Itoa(a.Port)
+	}
+	return a.Host + ":" + strconv.Itoa(a.Port)
+}
+
+// parseAddr parses host:port, where the port may be left out if def, the
+// default port, is not 0.  An IPv6 host is enclosed in brackets, but may be
+// given without them if there is no port.
+func parseAddr(s string, def int) (Addr, bool) {
+	host, port := s, ""
+	switch {
+	case strings.HasPrefix(s, "["):
+		i := strings.Index(s, "]")
+		if i < 0 {
+			return Addr{}, false
+		}
+		host, port = s[1:i], s[i+1:]
+		if net.ParseIP(host) == nil || port != "" && port[0] != ':' {
+			return Addr{}, false
+		}
+		if port != "" {
+			port = port[1:]
+		}
+	case strings.Count(s, ":") == 1:
+		i := strings.Index(s, ":")
+		host, port = s[0:i], s[i+1:]
+	case strings.Count(s, ":") > 1 && net.ParseIP(s) == nil:
+		return Addr{}, false
+	}
+	if port == "" {
+		return Addr{host, def}, def != 0
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return Addr{}, false
+	}
+	return Addr{host, n}, true
+}
+
+// A HardwareAddr is a hardware (MAC) address, such as an IEEE 802 MAC-48,
+// EUI-48 or EUI-64 address.
+type HardwareAddr []byte
+
+// String returns a in the form "01:23:45:67:89:ab".
+func (a HardwareAddr) String() string {
+	s := make([]string, len(a))
+	for i, b := range a {
+		s[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(s, ":")
+}
+
+// parseHardwareAddr parses a MAC-48, EUI-48, EUI-64 or 20-octet InfiniBand
+// address in one of the forms
+//
+//	01:23:45:67:89:ab
+//	01-23-45-67-89-ab
+//	0123.4567.89ab
+func parseHardwareAddr(s string) (HardwareAddr, bool) {
+	var groups []string
+	size := 2 // hex digits per group
+	switch {
+	case strings.Index(s, ":") >= 0:
+		groups = strings.Split(s, ":", -1)
+	case strings.Index(s, "-") >= 0:
+		groups = strings.Split(s, "-", -1)
+	default:
+		groups, size = strings.Split(s, ".", -1), 4
+	}
+	var a HardwareAddr
+	for _, g := range groups {
+		if len(g) != size {
+			return nil, false
+		}
+		for i := 0; i < size; i += 2 {
+			b, err := strconv.Btoui64(g[i:i+2], 16)
+			if err != nil {
+				return nil, false
+			}
+			a = append(a, byte(b))
+		}
+	}
+	switch len(a) {
+	case 6, 8, 20:
+		return a, true
+	}
+	return nil, false
+}
+
+// -- IP Value
+type ipValue struct {
+	p *net.IP
+}
+
+func newIPValue(val net.IP, p *net.IP) *ipValue {
+	*p = val
+	return &ipValue{p}
+}
+
+func (i *ipValue) set(s string) bool {
+	ip := parseIP(s)
+	if ip == nil {
+		return false
+	}
+	*i.p = ip
+	return true
+}
+
+func (i *ipValue) String() string {
+	if *i.p == nil {
+		return ""
+	}
+	return i.p.String()
+}
+
+// -- IPPrefix Value
+type prefixValue struct {
+	p *IPPrefix
+}
+
+func newPrefixValue(val IPPrefix, p *IPPrefix) *prefixValue {
+	*p = val
+	return &prefixValue{p}
+}
+
+func (p *prefixValue) set(s string) bool {
+	v, ok := parsePrefix(s)
+	if ok {
+		*p.p = v
+	}
+	return ok
+}
+
+func (p *prefixValue) String() string { return p.p.String() }
+
+// -- IPPrefixes Value
+type prefixesValue struct {
+	p *[]IPPrefix
+}
+
+func newPrefixesValue(val []IPPrefix, p *[]IPPrefix) *prefixesValue {
+	*p = val
+	return &prefixesValue{p}
+}
+
+func (p *prefixesValue) set(s string) bool {
+	v, ok := parsePrefix(s)
+	if ok {
+		*p.p = append(*p.p, v)
+	}
+	return ok
+}
+
+func (p *prefixesValue) String() string {
+	return joinValues(len(*p.p), func(n int) string { return (*p.p)[n].String() })
+}
+
+// -- Addr Value
+type addrValue struct {
+	p    *Addr
+	port int // the port of the default value
+}
+
+func newAddrValue(val Addr, p *Addr) *addrValue {
+	*p = val
+	return &addrValue{p, val.Port}
+}
+
+func (a *addrValue) set(s string) bool {
+	v, ok := parseAddr(s, a.port)
+	if ok {
+		*a.p = v
+	}
+	return ok
+}
+
+func (a *addrValue) String() string {
+	if a.p.Host == "" && a.p.Port == 0 {
+		return ""
+	}
+	return a.p.String()
+}
+
+// -- HardwareAddr Value
+type hardwareAddrValue struct {
+	p *HardwareAddr
+}
+
+func newHardwareAddrValue(val HardwareAddr, p *HardwareAddr) *hardwareAddrValue {
+	*p = val
+	return &hardwareAddrValue{p}
+}
+
+func (h *hardwareAddrValue) set(s string) bool {
+	v, ok := parseHardwareAddr(s)
+	if ok {
+		*h.p = v
+	}
+	return ok
+}
+
+func (h *hardwareAddrValue) String() string { return h.p.String() }
+
+// IPVar defines an IP address flag with specified name, default value, and usage
+// string.  The argument p points to a net.IP variable in which to store the value
+// of the flag.  IPv4 addresses are stored in their 4-byte form.
+func IPVar(p *net.IP, name, shortName string, value net.IP, usage string) {
+	add(name, shortName, newIPValue(value, p), usage)
+}
+
+// IP defines an IP address flag with specified name, default value, and usage
+// string.  The return value is the address of a net.IP variable that stores the
+// value of the flag.
+func IP(name, shortName string, value net.IP, usage string) *net.IP {
+	p := new(net.IP)
+	IPVar(p, name, shortName, value, usage)
+	return p
+}
+
+// PrefixVar defines a flag with specified name, default value, and usage string
+// for an IP network in CIDR notation, e.g. "10.0.0.0/8" or "fd00::/8".  The
+// argument p points to an IPPrefix variable in which to store the value of the
+// flag.
+func PrefixVar(p *IPPrefix, name, shortName string, value IPPrefix, usage string) {
+	add(name, shortName, newPrefixValue(value, p), usage)
+}
+
+// Prefix defines a flag with specified name, default value, and usage string for
+// an IP network in CIDR notation.  The return value is the address of an
+// IPPrefix variable that stores the value of the flag.
+func Prefix(name, shortName string, value IPPrefix, usage string) *IPPrefix {
+	p := new(IPPrefix)
+	PrefixVar(p, name, shortName, value, usage)
+	return p
+}
+
+// PrefixesVar defines a repeatable flag with specified name, default value, and
+// usage string for IP networks in CIDR notation.  The argument p points to an
+// []IPPrefix variable to which each argument of the flag is appended.
+func PrefixesVar(p *[]IPPrefix, name, shortName string, value []IPPrefix, usage string) {
+	add(name, shortName, newPrefixesValue(value, p), usage)
+}
+
+// Prefixes defines a repeatable flag with specified name, default value, and
+// usage string for IP networks in CIDR notation.  The return value is the address
+// of an []IPPrefix variable to which each argument of the flag is appended.
+func Prefixes(name, shortName string, value []IPPrefix, usage string) *[]IPPrefix {
+	p := new([]IPPrefix)
+	PrefixesVar(p, name, shortName, value, usage)
+	return p
+}
+
+// HostPortVar defines a host:port flag with specified name, default value, and
+// usage string.  The argument p points to an Addr variable in which to store the
+// value of the flag.  If the default value has a port, the port may be left out
+// of the argument, and the default port is used.
+func HostPortVar(p *Addr, name, shortName string, value Addr, usage string) {
+	add(name, shortName, newAddrValue(value, p), usage)
+}
+
+// HostPort defines a host:port flag with specified name, default value, and usage
+// string.  The return value is the address of an Addr variable that stores the
+// value of the flag.
+func HostPort(name, shortName string, value Addr, usage string) *Addr {
+	p := new(Addr)
+	HostPortVar(p, name, shortName, value, usage)
+	return p
+}
+
+// MACVar defines a hardware (MAC) address flag with specified name, default
+// value, and usage string.  The argument p points to a HardwareAddr variable in
+// which to store the value of the flag.
+func MACVar(p *HardwareAddr, name, shortName string, value HardwareAddr, usage string) {
+	add(name, shortName, newHardwareAddrValue(value, p), usage)
+}
+
+// MAC defines a hardware (MAC) address flag with specified name, default value,
+// and usage string.  The return value is the address of a HardwareAddr variable
+// that stores the value of the flag.
+func MAC(name, shortName string, value HardwareAddr, usage string) *HardwareAddr {
+	p := new(HardwareAddr)
+	MACVar(p, name, shortName, value, usage)
+	return p
+}
@@ -0,0 +1,144 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// An operand is a declared non-flag argument.
+type operand struct {
+	name     string
+	value    FlagValue
+	optional bool
+	variadic bool
+}
+
+// Operand declares a required non-flag argument, an operand in POSIX terms,
+// with the specified name, e.g. "DEST".  The argument p points to a variable
+// of any of the types flags can have, in which Parse stores the value of the
+// operand.  If p points to a slice, the operand is variadic: it takes one or
+// more arguments, as many as the other operands leave.
+//
+// Once operands are declared, Parse checks that the number of non-flag
+// arguments matches them, failing with "missing operand DEST" or
+// "extra operand 'x'", and converts their values.  Args still returns all of
+// them.  The default usage message shows a synopsis of the operands, e.g.
+//
+//	Usage: cp [OPTION]... SOURCE... DEST
+func Operand(p interface{}, name string) { addOperand(p, name, false) }
+
+// OptionalOperand declares an optional non-flag argument, as Operand does.
+// Optional operands follow all required ones; if variadic, it takes zero or
+// more arguments.
+func OptionalOperand(p interface{}, name string) { addOperand(p, name, true) }
+
+func addOperand(p interface{}, name string, optional bool) {
+	value := valueOf(p)
+	if value == nil {
+		fmt.Fprintln(os.Stderr, "operand type unsupported:", name)
+		panic("operand type unsupported")
+	}
+	op := &operand{name, value, optional, isList(value)}
+	for _, prev := range flags.operands {
+		switch {
+		case prev.name == name:
+			fmt.Fprintln(os.Stderr, "operand redefined:", name)
+			panic("operand redefinition")
+		case prev.variadic && op.variadic:
+			fmt.Fprintln(os.Stderr, "second variadic operand:", name)
+			panic("second variadic operand")
+		case prev.optional && !op.optional,
+			prev.optional && prev.variadic,
+			prev.variadic && op.optional:
+			// Which arguments go where would be ambiguous.
+			fmt.Fprintln(os.Stderr, "operand ambiguous:", name)
+			panic("operand ambiguous")
+		}
+	}
+	flags.operands = append(flags.operands, op)
+}
+
+// synopsis returns the synopsis of the declared operands, such as
+// "SOURCE... DEST" or "[FILE]...".
+func (f *allFlags) synopsis() string {
+	var words []string
+	for _, op := range f.operands {
+		w := op.name
+		if op.optional {
+			w = "[" + w + "]"
+		}
+		if op.variadic {
+			w += "..."
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " ")
+}
+
+// parseOperands stores args, the non-flag arguments, in the declared operands.
+// It returns an error if they don't match.
+func (f *allFlags) parseOperands(args []string) *Error {
+	if len(f.operands) == 0 {
+		return nil
+	}
+	// The operands after a variadic one (which are all required) take the
+	// last arguments; the others take them in order.
+	before, variadic, after := f.operands, (*operand)(nil), []*operand(nil)
+	var required []*operand
+	for i, op := range f.operands {
+		if op.variadic {
+			before, variadic, after = f.operands[0:i], op, f.operands[i+1:]
+		}
+		if !op.optional {
+			required = append(required, op)
+		}
+	}
+	if len(args) < len(required) {
+		return &Error{Kind: BadOperands, Msg: fmt.Sprintf(Gettext("missing operand %s"), required[len(args)].name)}
+	}
+	end := len(args) - len(after)
+	i := 0
+	for _, op := range before {
+		if i == end {
+			break // only optional operands are left
+		}
+		if !op.value.set(args[i]) {
+			return &Error{Kind: InvalidValue, Value: args[i], Msg: fmt.Sprintf(Gettext("invalid value %s for operand %s"), args[i], op.name)}
+		}
+		i++
+	}
+	for ; variadic != nil && i < end; i++ {
+		if !variadic.value.set(args[i]) {
+			return &Error{Kind: InvalidValue, Value: args[i], Msg: fmt.Sprintf(Gettext("invalid value %s for operand %s"), args[i], variadic.name)}
+		}
+	}
+	if i < end {
+		return &Error{Kind: BadOperands, Msg: fmt.Sprintf(Gettext("extra operand '%s'"), args[i])}
+	}
+	for _, op := range after {
+		if !op.value.set(args[i]) {
+			return &Error{Kind: InvalidValue, Value: args[i], Msg: fmt.Sprintf(Gettext("invalid value %s for operand %s"), args[i], op.name)}
+		}
+		i++
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// PathOptions constrain the paths a path flag accepts.  They are checked when
+// the flag is parsed, against the file system as it is then.
+type PathOptions struct {
+	MustExist    bool   // the path must exist
+	MustNotExist bool   // the path must not exist
+	File         bool   // the path, if it exists, must be a regular file
+	Dir          bool   // the path, if it exists, must be a directory
+	ParentExists bool   // the directory the path is in must exist
+	Writable     bool   // the path, or the directory it is in if it does not exist, must be writable
+	Glob         string // the files shell completion offers, e.g. "*.conf"; any if empty
+}
+
+// expandPath returns s with a leading "~" replaced by $HOME, made absolute
+// relative to the working directory and cleaned.
+func expandPath(s string) (string, os.Error) {
+	if s == "" {
+		return "", os.NewError("empty path")
+	}
+	if s == "~" || strings.HasPrefix(s, "~/") {
+		home := os.Getenv("HOME")
+		if home == "" {
+			return "", os.NewError("cannot expand ~: HOME is not set")
+		}
+		s = home + s[1:]
+	}
+	if s[0] != '/' {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		s = wd + "/" + s
+	}
+	return path.Clean(s), nil
+}
+
+// check expands s and returns it if it meets o.
+func (o *PathOptions) check(s string) (string, os.Error) {
+	p, err := expandPath(s)
+	if err != nil {
+		return "", err
+	}
+	fi, err := os.Stat(p)
+	switch {
+	case err != nil && o.MustExist:
+		return "", err
+	case err == nil && o.MustNotExist:
+		return "", os.NewError(p + ": file exists")
+	case err == nil && o.Dir && !fi.IsDirectory():
+		return "", os.NewError(p + ": not a directory")
+	case err == nil && o.File && !fi.IsRegular():
+		return "", os.NewError(p + ": not a regular file")
+	}
+	if o.ParentExists {
+		dir := path.Dir(p)
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return "", err
+		}
+		if !fi.IsDirectory() {
+			return "", os.NewError(dir + ": not a directory")
+		}
+	}
+	if o.Writable {
+		target := p
+		if err != nil {
+			target = path.Dir(p) // the path would be created there
+		}
+		if e := syscall.Access(target, wOK); e != 0 {
+			return "", &os.PathError{Op: "access", Path: target, Error: os.Errno(e)}
+		}
+	}
+	return p, nil
+}
+
+// wOK is the access(2) mode that asks whether a file may be written.
+const wOK = 2
+
+// zsh returns the _arguments action that completes the paths o accepts.
+func (o *PathOptions) zsh() string {
+	switch {
+	case o.Dir:
+		return "_files -/"
+	case o.Glob != "":
+		return "_files -g \"" + o.Glob + "\""
+	}
+	return "_files"
+}
+
+// fish returns the options of the fish complete command that complete the
+// paths o accepts.  Fish can only filter files by suffix, so other globs
+// offer every file.
+func (o *PathOptions) fish() string {
+	switch {
+	case o.Dir:
+		return "-x -a " + fishQuote("(__fish_complete_directories)")
+	case strings.HasPrefix(o.Glob, "*.") && !hasGlobMeta(o.Glob[1:]):
+		return "-x -a " + fishQuote("(__fish_complete_suffix "+o.Glob[1:]+")")
+	}
+	return "-r"
+}
+
+// hasGlobMeta reports whether the glob s has any special characters.
+func hasGlobMeta(s string) bool {
+	for _, c := range s {
+		if c == '*' || c == '?' || c == '[' {
+			return true
+		}
+	}
+	return false
+}
+
+// metavar returns the name of the argument of a flag taking paths o accepts.
+func (o *PathOptions) metavar() string {
+	if o.Dir {
+		return "DIR"
+	}
+	return "FILE"
+}
+
+// -- Path Value
+type pathValue struct {
+	p    *string
+	opts PathOptions
+	last string // the path last set, which was given as raw
+	raw  string
+	err  os.Error // why the last path was rejected
+}
+
+func newPathValue(val, raw string, opts PathOptions, p *string) *pathValue {
+	*p = val
+	return &pathValue{p, opts, val, raw, nil}
+}
+
+func (v *pathValue) set(s string) bool {
+	p, err := v.opts.check(s)
+	if v.err = err; err != nil {
+		return false
+	}
+	*v.p, v.last, v.raw = p, p, s
+	return true
+}
+
+func (v *pathValue) reason() os.Error { return v.err }
+
+// String returns the path as it was given, before expansion, unless the
+// program has changed it since.
+func (v *pathValue) String() string {
+	if *v.p != v.last {
+		return *v.p
+	}
+	return v.raw
+}
+
+// -- Paths Value
+type pathsValue struct {
+	p    *[]string
+	opts PathOptions
+	raw  []string // the argument each path was given as
+	err  os.Error
+}
+
+func newPathsValue(val, raw []string, opts PathOptions, p *[]string) *pathsValue {
+	*p = val
+	return &pathsValue{p, opts, raw, nil}
+}
+
+func (v *pathsValue) set(s string) bool {
+	p, err := v.opts.check(s)
+	if v.err = err; err != nil {
+		return false
+	}
+	*v.p = append(*v.p, p)
+	v.raw = append(v.raw, s)
+	return true
+}
+
+func (v *pathsValue) reason() os.Error { return v.err }
+
+func (v *pathsValue) String() string { return strings.Join(v.raw, ",") }
+
+// expandDefault returns the default path s expanded, or as it is if it cannot
+// be.  Defaults are not checked against the options, since a program may well
+// default to a file that is not there yet.
+func expandDefault(s string) string {
+	if p, err := expandPath(s); err == nil {
+		return p
+	}
+	return s
+}
+
+// PathVar defines a path flag with specified name, default value, and usage
+// string.  The argument p points to a string variable in which to store the
+// value of the flag.  Paths are expanded, with a leading "~" standing for
+// $HOME and relative paths made absolute, and checked against opts as the flag
+// is parsed; a path that fails is reported as an invalid value of the flag,
+// with the reason as the Err of the Error.  An empty default value leaves the
+// variable empty.
+func PathVar(p *string, name, shortName string, value string, opts PathOptions, usage string) {
+	def := ""
+	if value != "" {
+		def = expandDefault(value)
+	}
+	add(name, shortName, newPathValue(def, value, opts, p), usage)
+}
+
+// Path defines a path flag with specified name, default value, and usage
+// string.  The return value is the address of a string variable that stores
+// the value of the flag.
+func Path(name, shortName string, value string, opts PathOptions, usage string) *string {
+	p := new(string)
+	PathVar(p, name, shortName, value, opts, usage)
+	return p
+}
+
+// PathsVar defines a repeatable path flag with specified name, default value,
+// and usage string.  The argument p points to an []string variable to which
+// each argument of the flag is appended once expanded and checked.
+func PathsVar(p *[]string, name, shortName string, value []string, opts PathOptions, usage string) {
+	def := make([]string, len(value))
+	for i, s := range value {
+		def[i] = expandDefault(s)
+	}
+	add(name, shortName, newPathsValue(def, append([]string(nil), value...), opts, p), usage)
+}
+
+// Paths defines a repeatable path flag with specified name, default value, and
+// usage string.  The return value is the address of an []string variable to
+// which each argument of the flag is appended.
+func Paths(name, shortName string, value []string, opts PathOptions, usage string) *[]string {
+	p := new([]string)
+	PathsVar(p, name, shortName, value, opts, usage)
+	return p
+}
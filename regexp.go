@@ -0,0 +1,218 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// RegexpOptions control how the patterns of a regular expression flag are
+// compiled.
+//
+// The regexp package has no leftmost-longest (POSIX) matching mode, so none is
+// offered here; a pattern matches as the regexp package always does.
+type RegexpOptions struct {
+	IgnoreCase bool // whether letters match regardless of case
+}
+
+// compile compiles the pattern s as o directs.
+func (o *RegexpOptions) compile(s string) (*regexp.Regexp, os.Error) {
+	if o.IgnoreCase {
+		s = foldCase(s)
+	}
+	return regexp.Compile(s)
+}
+
+// foldCase rewrites the regular expression s so that each letter matches in
+// either case: "ab[a-c]" becomes "[aA][bB][a-cA-C]".  Escaped characters are
+// left alone, as are ranges whose ends are not letters of the same case.
+func foldCase(s string) string {
+	r := []int(s)
+	var out []int
+	class := false // inside a character class
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+		switch {
+		case c == '\\' && i+1 < len(r):
+			out = append(out, c, r[i+1])
+			i++
+		case class && c == ']':
+			out = append(out, c)
+			class = false
+		case class && i+2 < len(r) && r[i+1] == '-' && r[i+2] != ']':
+			lo, hi := c, r[i+2]
+			out = append(out, lo, '-', hi)
+			if unicode.IsLower(lo) && unicode.IsLower(hi) || unicode.IsUpper(lo) && unicode.IsUpper(hi) {
+				out = append(out, swapCase(lo), '-', swapCase(hi))
+			}
+			i += 2
+		case class:
+			out = append(out, c)
+			if swapCase(c) != c {
+				out = append(out, swapCase(c))
+			}
+		case c == '[':
+			out = append(out, c)
+			class = true
+			if i+1 < len(r) && r[i+1] == '^' {
+				out = append(out, '^')
+				i++
+			}
+		case swapCase(c) != c:
+			out = append(out, '[', c, swapCase(c), ']')
+		default:
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+// swapCase returns the letter c in the other case, or c if it has none.
+func swapCase(c int) int {
+	if unicode.IsUpper(c) {
+		return unicode.ToLower(c)
+	}
+	return unicode.ToUpper(c)
+}
+
+// A reasoner is a FlagValue that can tell why it rejected the last value it
+// was given, so that the Error reporting it can carry the reason.
+type reasoner interface {
+	reason() os.Error
+}
+
+// -- Regexp Value
+type regexpValue struct {
+	p      **regexp.Regexp
+	opts   RegexpOptions
+	last   *regexp.Regexp // the expression last set, which was compiled from source
+	source string
+	err    os.Error // why the last pattern was rejected
+}
+
+func newRegexpValue(val *regexp.Regexp, source string, opts RegexpOptions, p **regexp.Regexp) *regexpValue {
+	*p = val
+	return &regexpValue{p, opts, val, source, nil}
+}
+
+func (r *regexpValue) set(s string) bool {
+	v, err := r.opts.compile(s)
+	if r.err = err; err != nil {
+		return false
+	}
+	*r.p, r.last, r.source = v, v, s
+	return true
+}
+
+func (r *regexpValue) reason() os.Error { return r.err }
+
+// String returns the pattern the regular expression was given as, or nothing
+// if the program has changed it since.
+func (r *regexpValue) String() string {
+	if *r.p != r.last {
+		return ""
+	}
+	return r.source
+}
+
+// -- Regexps Value
+type regexpsValue struct {
+	p       *[]*regexp.Regexp
+	opts    RegexpOptions
+	sources []string // the pattern each expression was compiled from
+	err     os.Error
+}
+
+func newRegexpsValue(val []*regexp.Regexp, sources []string, opts RegexpOptions, p *[]*regexp.Regexp) *regexpsValue {
+	*p = val
+	return &regexpsValue{p, opts, sources, nil}
+}
+
+func (r *regexpsValue) set(s string) bool {
+	v, err := r.opts.compile(s)
+	if r.err = err; err != nil {
+		return false
+	}
+	*r.p = append(*r.p, v)
+	r.sources = append(r.sources, s)
+	return true
+}
+
+func (r *regexpsValue) reason() os.Error { return r.err }
+
+func (r *regexpsValue) String() string { return strings.Join(r.sources, ",") }
+
+// compileDefault compiles the default pattern s of the flag name, and panics
+// if it is invalid.
+func compileDefault(name, s string, opts RegexpOptions) *regexp.Regexp {
+	re, err := opts.compile(s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "flag default invalid:", name, err)
+		panic("flag default invalid")
+	}
+	return re
+}
+
+// RegexpVar defines a regular expression flag with specified name, default
+// value, and usage string.  The argument p points to a *regexp.Regexp variable
+// in which to store the value of the flag.  Patterns are compiled as opts
+// directs when the flag is parsed, so a bad one is reported as an invalid value
+// of the flag, with the compile error as the Err of the Error.  An empty
+// default value leaves the variable nil.
+func RegexpVar(p **regexp.Regexp, name, shortName string, value string, opts RegexpOptions, usage string) {
+	var def *regexp.Regexp
+	if value != "" {
+		def = compileDefault(name, value, opts)
+	}
+	add(name, shortName, newRegexpValue(def, value, opts, p), usage)
+}
+
+// Regexp defines a regular expression flag with specified name, default value,
+// and usage string.  The return value is the address of a *regexp.Regexp
+// variable that stores the value of the flag.
+func Regexp(name, shortName string, value string, opts RegexpOptions, usage string) **regexp.Regexp {
+	p := new(*regexp.Regexp)
+	RegexpVar(p, name, shortName, value, opts, usage)
+	return p
+}
+
+// RegexpsVar defines a repeatable regular expression flag with specified name,
+// default value, and usage string.  The argument p points to a []*regexp.Regexp
+// variable to which each argument of the flag is appended once compiled; the
+// default value is a list of patterns.
+func RegexpsVar(p *[]*regexp.Regexp, name, shortName string, value []string, opts RegexpOptions, usage string) {
+	def := make([]*regexp.Regexp, len(value))
+	for i, s := range value {
+		def[i] = compileDefault(name, s, opts)
+	}
+	add(name, shortName, newRegexpsValue(def, append([]string(nil), value...), opts, p), usage)
+}
+
+// Regexps defines a repeatable regular expression flag with specified name,
+// default value, and usage string.  The return value is the address of a
+// []*regexp.Regexp variable to which each argument of the flag is appended.
+func Regexps(name, shortName string, value []string, opts RegexpOptions, usage string) *[]*regexp.Regexp {
+	p := new([]*regexp.Regexp)
+	RegexpsVar(p, name, shortName, value, opts, usage)
+	return p
+}
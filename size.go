@@ -0,0 +1,159 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// sizeUnits are the unit prefixes of sizes, in increasing order.
+const sizeUnits = "KMGTPE"
+
+// maxFraction is the number of digits after the decimal point that parseSize
+// takes into account, so that its arithmetic cannot overflow.
+const maxFraction = 15
+
+// -- Size Value
+type sizeValue struct {
+	p *uint64
+}
+
+func newSizeValue(val uint64, p *uint64) *sizeValue {
+	*p = val
+	return &sizeValue{p}
+}
+
+func (s *sizeValue) set(val string) bool {
+	v, ok := parseSize(val)
+	if ok {
+		*s.p = v
+	}
+	return ok
+}
+
+func (s *sizeValue) String() string { return formatSize(*s.p) }
+
+// parseSize parses a size as the coreutils do: a number, possibly with a
+// fraction, followed by an optional unit.  The units K, M, G, T, P and E (or
+// k for K) are powers of 1024, as are KiB, MiB and so on; KB, MB and so on are
+// powers of 1000.  A B alone stands for bytes.  It returns false if s is not
+// a size or the size doesn't fit in a uint64.
+func parseSize(s string) (uint64, bool) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	number, unit := s[0:i], s[i:]
+	whole, frac := number, ""
+	if dot := strings.Index(number, "."); dot >= 0 {
+		whole, frac = number[0:dot], number[dot+1:]
+	}
+	if whole == "" && frac == "" || strings.Index(frac, ".") >= 0 {
+		return 0, false
+	}
+	// Find the multiplier, base^power.
+	base, power := uint64(1024), 0
+	if unit != "" && unit != "B" {
+		power = strings.Index(sizeUnits, strings.ToUpper(unit[0:1])) + 1
+		if power == 0 || unit[0] != 'k' && unit[0] >= 'a' {
+			return 0, false
+		}
+		switch unit[1:] {
+		case "", "iB":
+		case "B":
+			base = 1000
+		default:
+			return 0, false
+		}
+	}
+	var n uint64
+	if whole != "" {
+		v, err := strconv.Atoui64(whole)
+		if err != nil {
+			return 0, false
+		}
+		n = v
+	}
+	// Multiply the whole part and the fraction, num/den, by the base
+	// power times, carrying what the fraction contributes to the whole.
+	if len(frac) > maxFraction {
+		frac = frac[0:maxFraction]
+	}
+	num, den := uint64(0), uint64(1)
+	for _, c := range frac {
+		num, den = num*10+uint64(c-'0'), den*10
+	}
+	if power == 0 && num != 0 {
+		return 0, false // a fraction of a byte
+	}
+	const max = 1<<64 - 1
+	for ; power > 0; power-- {
+		num *= base
+		if n > (max-num/den)/base {
+			return 0, false
+		}
+		n = n*base + num/den
+		num %= den
+	}
+	return n, true
+}
+
+// formatSize returns n in the largest unit that it is a whole number of, such
+// as "64KiB" or "5MB", or as a number of bytes if there is none.
+func formatSize(n uint64) string {
+	if n == 0 {
+		return "0"
+	}
+	for power := len(sizeUnits); power > 0; power-- {
+		for _, base := range []uint64{1024, 1000} {
+			m := uint64(1)
+			for i := 0; i < power; i++ {
+				m *= base
+			}
+			if n%m == 0 {
+				unit := sizeUnits[power-1:power] + "iB"
+				if base == 1000 {
+					unit = sizeUnits[power-1:power] + "B"
+				}
+				return strconv.Uitoa64(n/m) + unit
+			}
+		}
+	}
+	return strconv.Uitoa64(n)
+}
+
+// SizeVar defines a size flag with specified name, default value, and usage string.
+// The argument p points to a uint64 variable in which to store the size, in
+// bytes.  Sizes are given as in the coreutils, e.g. "10K", "1.5MiB" or "2GB":
+// K, M, G, T, P and E, alone or followed by iB, are powers of 1024, and
+// followed by B powers of 1000.  Help output shows the default in the same
+// form.
+func SizeVar(p *uint64, name, shortName string, value uint64, usage string) {
+	add(name, shortName, newSizeValue(value, p), usage)
+}
+
+// Size defines a size flag with specified name, default value, and usage string.
+// The return value is the address of a uint64 variable that stores the size, in
+// bytes.
+func Size(name, shortName string, value uint64, usage string) *uint64 {
+	p := new(uint64)
+	SizeVar(p, name, shortName, value, usage)
+	return p
+}
@@ -0,0 +1,106 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A SourceKind says where the value of a flag came from.
+type SourceKind int
+
+const (
+	FromDefault SourceKind = iota // the default value
+	FromEnv                       // an environment variable
+	FromFile                      // a configuration file, through SetFrom
+	FromArgs                      // the command line
+	FromProgram                   // the program itself, through Set
+)
+
+// A Source says where the value of a flag came from.
+type Source struct {
+	Kind  SourceKind
+	Name  string // the environment variable or the file name
+	Line  int    // the line in the file, if known
+	Index int    // the index of the option in os.Args
+}
+
+// String returns a short description of s, as in "$HOME", "app.conf:12" or
+// "argument 3".
+func (s Source) String() string {
+	switch s.Kind {
+	case FromEnv:
+		return "$" + s.Name
+	case FromFile:
+		if s.Line > 0 {
+			return s.Name + ":" + strconv.Itoa(s.Line)
+		}
+		return s.Name
+	case FromArgs:
+		return fmt.Sprintf(Gettext("argument %d"), s.Index)
+	case FromProgram:
+		return Gettext("program")
+	}
+	return Gettext("default")
+}
+
+// SetFrom sets the value of the named flag, as Set does, recording src as its
+// source.  It is meant for configuration loaders, which call it before Parse
+// so that the environment and the command line take precedence.
+func SetFrom(name, value string, src Source) bool {
+	f, ok := flags.formal[name]
+	if !ok || flags.apply(f, "--"+name, value, src) != nil {
+		return false
+	}
+	flags.mark(f)
+	return true
+}
+
+// DefinePrintConfig defines the --print-config flag, which makes Parse print
+// the configuration, as PrintConfig does, to standard output once the command
+// line has been parsed, and exit successfully.  The flag is persistent, so
+// that "tool COMMAND --print-config" includes the flags of COMMAND.
+func DefinePrintConfig() {
+	Bool("print-config", "", false, "print the value of each option and where it came from, and exit")
+	flags.printConfig = flags.formal["print-config"]
+	flags.printConfig.Persistent = true
+}
+
+// PrintConfig prints to w the value of each flag listed in help output, in
+// the order they were defined, along with its source:
+//
+//	verbose = true  # argument 1
+//	output = "out.txt"  # $OUTPUT
+//	level = 3  # default
+//
+// The built-in flags are left out.
+func PrintConfig(w io.Writer) {
+	for _, f := range flags.order {
+		if !listed(f) || f == flags.help || f == flags.version || f == flags.printConfig {
+			continue
+		}
+		value := f.Value.String()
+		if _, ok := f.Value.(*stringValue); ok {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(w, "%s = %s  # %s\n", f.Name, value, f.Source)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// RegisterStruct defines a flag for each exported field of the struct v points
+// to, which is bound to the field.  The flags are described by the field tags:
+//
+//	type Config struct {
+//		Listen  string   `flag:"listen,l" usage:"listen on ADDR" default:":8080" env:"APP_LISTEN"`
+//		Verbose bool     `flag:",v" usage:"be verbose"`
+//		Include []string `usage:"also read FILE"`
+//		Debug   bool     `flag:"-"`
+//		DB      struct {
+//			Host string `usage:"database host"`
+//		}
+//	}
+//
+// The flag tag gives the long and short names, separated by a comma; the long
+// name is derived from the field name if it is empty ("Include" becomes
+// --include, "MaxSize" --max-size), and a name of "-" skips the field.  The
+// usage, default and env tags give the usage string, the default value and the
+// environment variable (see SetEnv) of the flag.  Without a default tag, the
+// current value of the field is the default.  For slice fields, both the
+// default and the environment variable hold values separated by commas.  The fields of a nested struct
+// are flags too, with the name of the struct field as a prefix: --db-host
+// above.
+//
+// Fields may be of type bool, int, int64, uint, uint64, string, float or
+// float64, or slices of those but bool and float, which make repeatable
+// flags.  RegisterStruct returns an error for fields of other types.
+func RegisterStruct(v interface{}) os.Error {
+	p, ok := reflect.NewValue(v).(*reflect.PtrValue)
+	if !ok {
+		return os.NewError("gnuflag: RegisterStruct of non-pointer")
+	}
+	s, ok := p.Elem().(*reflect.StructValue)
+	if !ok {
+		return os.NewError("gnuflag: RegisterStruct of pointer to non-struct")
+	}
+	return registerStruct(s, "")
+}
+
+// ParseStruct registers the fields of the struct v points to as flags, as
+// RegisterStruct does, and then parses the command line.
+func ParseStruct(v interface{}) os.Error {
+	if err := RegisterStruct(v); err != nil {
+		return err
+	}
+	Parse()
+	return nil
+}
+
+func registerStruct(s *reflect.StructValue, prefix string) os.Error {
+	t := s.Type().(*reflect.StructType)
+	for i := 0; i < s.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		name, shortName := tagValue(field.Tag, "flag"), ""
+		if name == "-" {
+			continue
+		}
+		if j := strings.Index(name, ","); j >= 0 {
+			name, shortName = name[0:j], name[j+1:]
+		}
+		if name == "" {
+			name = flagName(field.Name)
+		}
+		name = prefix + name
+		if sv, ok := s.Field(i).(*reflect.StructValue); ok {
+			if err := registerStruct(sv, name+"-"); err != nil {
+				return err
+			}
+			continue
+		}
+		value := valueOf(fieldPointer(s.Field(i).Addr(), field.Type))
+		if value == nil {
+			return os.NewError("gnuflag: field " + field.Name + " has unsupported type " + field.Type.String())
+		}
+		f := add(name, shortName, value, tagValue(field.Tag, "usage"))
+		if def := tagValue(field.Tag, "default"); def != "" {
+			values := []string{def}
+			if isList(f.Value) {
+				values = strings.Split(def, ",", -1)
+			}
+			for _, v := range values {
+				if !f.Value.set(v) {
+					return os.NewError("gnuflag: invalid default " + def + " for field " + field.Name)
+				}
+			}
+			f.DefValue = f.Value.String()
+		}
+		f.Env = tagValue(field.Tag, "env")
+	}
+	return nil
+}
+
+// fieldPointer returns a pointer to the variable of type t at addr, if t is
+// a type RegisterStruct supports, and nil otherwise.
+func fieldPointer(addr uintptr, t reflect.Type) interface{} {
+	p := unsafe.Pointer(addr)
+	switch t.Kind() {
+	case reflect.Bool:
+		return (*bool)(p)
+	case reflect.Int:
+		return (*int)(p)
+	case reflect.Int64:
+		return (*int64)(p)
+	case reflect.Uint:
+		return (*uint)(p)
+	case reflect.Uint64:
+		return (*uint64)(p)
+	case reflect.String:
+		return (*string)(p)
+	case reflect.Float:
+		return (*float)(p)
+	case reflect.Float64:
+		return (*float64)(p)
+	case reflect.Slice:
+		switch t.(*reflect.SliceType).Elem().Kind() {
+		case reflect.Int:
+			return (*[]int)(p)
+		case reflect.Int64:
+			return (*[]int64)(p)
+		case reflect.Uint:
+			return (*[]uint)(p)
+		case reflect.Uint64:
+			return (*[]uint64)(p)
+		case reflect.String:
+			return (*[]string)(p)
+		case reflect.Float64:
+			return (*[]float64)(p)
+		}
+	}
+	return nil
+}
+
+// tagValue returns the value of key in a struct tag made of space-separated
+// key:"value" pairs, where the values are quoted Go strings.
+func tagValue(tag, key string) string {
+	for {
+		tag = strings.TrimLeft(tag, " ")
+		i := strings.Index(tag, ":\"")
+		if i <= 0 {
+			return ""
+		}
+		name := tag[0:i]
+		tag = tag[i+1:]
+		// Find the closing quote, skipping escaped characters.
+		j := 1
+		for j < len(tag) && tag[j] != '"' {
+			if tag[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(tag) {
+			return ""
+		}
+		if name == key {
+			v, err := strconv.Unquote(tag[0 : j+1])
+			if err != nil {
+				return ""
+			}
+			return v
+		}
+		tag = tag[j+1:]
+	}
+	panic("unreachable")
+}
+
+// flagName derives a flag name from the name of a struct field, by lower-casing
+// it and separating words with hyphens: MaxSize becomes max-size and
+// HTTPPort http-port.
+func flagName(field string) string {
+	var name []int
+	runes := []int(field)
+	for i, r := range runes {
+		upper := 'A' <= r && r <= 'Z'
+		if upper && i > 0 {
+			prevLower := 'a' <= runes[i-1] && runes[i-1] <= 'z'
+			nextLower := i+1 < len(runes) && 'a' <= runes[i+1] && runes[i+1] <= 'z'
+			prevUpper := 'A' <= runes[i-1] && runes[i-1] <= 'Z'
+			if prevLower || prevUpper && nextLower {
+				name = append(name, '-')
+			}
+		}
+		if upper {
+			r += 'a' - 'A'
+		}
+		name = append(name, r)
+	}
+	return string(name)
+}
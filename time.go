@@ -0,0 +1,148 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeLayouts are the layouts, as for time.Parse, that time flags accept
+// unless their TimeOptions say otherwise: RFC 3339, the same without a time
+// zone, "YYYY-MM-DD HH:MM[:SS]" and a date alone.
+var TimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// TimeOptions control how a time flag parses times.
+type TimeOptions struct {
+	Layouts []string // the layouts accepted; TimeLayouts if empty
+
+	// Times given without a time zone are in the local time zone if Local
+	// is set, and otherwise in the zone ZoneOffset seconds east of UTC,
+	// named Zone.  The default is UTC.
+	Local      bool
+	ZoneOffset int
+	Zone       string
+}
+
+// in returns the time sec seconds after the Unix epoch in the zone of o.
+func (o *TimeOptions) in(sec int64) *time.Time {
+	if o.Local {
+		return time.SecondsToLocalTime(sec)
+	}
+	t := time.SecondsToUTC(sec + int64(o.ZoneOffset))
+	if o.ZoneOffset != 0 || o.Zone != "" {
+		t.ZoneOffset, t.Zone = o.ZoneOffset, o.Zone
+	}
+	return t
+}
+
+// offset returns the offset from UTC, in seconds, of the zone of o at about
+// the time that reads as wall seconds after the epoch on a clock in that zone.
+func (o *TimeOptions) offset(wall int64) int64 {
+	if o.Local {
+		return int64(time.SecondsToLocalTime(wall).ZoneOffset)
+	}
+	return int64(o.ZoneOffset)
+}
+
+// parse parses s as a time in one of the layouts of o or, as GNU date
+// accepts, as "@" followed by the number of seconds since the Unix epoch.
+func (o *TimeOptions) parse(s string) (*time.Time, bool) {
+	if strings.HasPrefix(s, "@") {
+		sec, err := strconv.Atoi64(s[1:])
+		if err != nil {
+			return nil, false
+		}
+		return o.in(sec), true
+	}
+	layouts := o.Layouts
+	if len(layouts) == 0 {
+		layouts = TimeLayouts
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if strings.Index(layout, "07") >= 0 || strings.Index(layout, "MST") >= 0 {
+			return t, true // the time has its own zone
+		}
+		wall := t.Seconds()
+		return o.in(wall - o.offset(wall)), true
+	}
+	return nil, false
+}
+
+// -- Time Value
+type timeValue struct {
+	p        *time.Time
+	opts     TimeOptions
+	relative bool // whether relative dates are accepted
+}
+
+func newTimeValue(val time.Time, opts TimeOptions, p *time.Time) *timeValue {
+	*p = val
+	return &timeValue{p, opts, false}
+}
+
+func (t *timeValue) set(s string) bool {
+	var v *time.Time
+	var ok bool
+	if t.relative {
+		v, ok = t.opts.parseDate(s)
+	} else {
+		v, ok = t.opts.parse(s)
+	}
+	if ok {
+		*t.p = *v
+	}
+	return ok
+}
+
+func (t *timeValue) String() string {
+	if t.p.Month == 0 {
+		return "" // the zero Time
+	}
+	return t.p.Format(time.RFC3339)
+}
+
+// TimeVar defines a time flag with specified name, default value, and usage string.
+// The argument p points to a time.Time variable in which to store the value of the
+// flag.  The times accepted are described by opts; by default they are those of
+// TimeLayouts, in UTC, and "@" followed by seconds since the Unix epoch.  Help
+// output shows the default in RFC 3339 format.
+func TimeVar(p *time.Time, name, shortName string, value time.Time, opts TimeOptions, usage string) {
+	add(name, shortName, newTimeValue(value, opts, p), usage)
+}
+
+// Time defines a time flag with specified name, default value, and usage string.
+// The return value is the address of a time.Time variable that stores the value of
+// the flag.
+func Time(name, shortName string, value time.Time, opts TimeOptions, usage string) *time.Time {
+	p := new(time.Time)
+	TimeVar(p, name, shortName, value, opts, usage)
+	return p
+}
@@ -0,0 +1,142 @@
+/*
+	gnuflag - A 'flag'-like package for handling GNU-style program options
+	Copyright (C) 2010  Conrad Meyer <cemeyer@cs.washington.edu>
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU General Public License for more details.
+
+	You should have received a copy of the GNU General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gnuflag
+
+import (
+	"fmt"
+	"http"
+	"os"
+	"strings"
+)
+
+// URLOptions restrict the URLs a URL flag accepts.
+type URLOptions struct {
+	Schemes       []string // the schemes accepted, e.g. "http" and "https"; any if empty
+	RequireHost   bool     // whether the URL must have a host
+	DefaultScheme string   // the scheme of URLs given without one, e.g. "example.com/x"
+}
+
+// complete returns s with the default scheme of o added if it has none.
+func (o *URLOptions) complete(s string) string {
+	if o.DefaultScheme != "" && strings.Index(s, "://") < 0 {
+		return o.DefaultScheme + "://" + s
+	}
+	return s
+}
+
+// parse parses s as a URL meeting o.
+func (o *URLOptions) parse(s string) (*http.URL, bool) {
+	u, err := http.ParseURL(o.complete(s))
+	if err != nil {
+		return nil, false
+	}
+	if o.RequireHost && u.Host == "" {
+		return nil, false
+	}
+	if len(o.Schemes) == 0 {
+		return u, true
+	}
+	for _, scheme := range o.Schemes {
+		if strings.ToLower(u.Scheme) == strings.ToLower(scheme) {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// redactURL returns the URL s with the password in its userinfo, if any,
+// replaced by "xxxxx", so that it can be shown.
+func redactURL(s string) string {
+	i := strings.Index(s, "://")
+	if i < 0 {
+		return s
+	}
+	authority := s[i+3:]
+	if j := strings.Index(authority, "/"); j >= 0 {
+		authority = authority[0:j]
+	}
+	at := strings.LastIndex(authority, "@")
+	if at < 0 {
+		return s
+	}
+	colon := strings.Index(authority[0:at], ":")
+	if colon < 0 {
+		return s
+	}
+	return s[0:i+3+colon+1] + "xxxxx" + s[i+3+at:]
+}
+
+// -- URL Value
+type urlValue struct {
+	p    **http.URL
+	opts URLOptions
+	last *http.URL // the URL last set, which was given as raw
+	raw  string
+}
+
+func newURLValue(val *http.URL, raw string, opts URLOptions, p **http.URL) *urlValue {
+	*p = val
+	return &urlValue{p, opts, val, raw}
+}
+
+func (u *urlValue) set(s string) bool {
+	v, ok := u.opts.parse(s)
+	if ok {
+		*u.p, u.last, u.raw = v, v, u.opts.complete(s)
+	}
+	return ok
+}
+
+// String returns the URL as it was given, with the default scheme, unless the
+// program has changed it since.
+func (u *urlValue) String() string {
+	switch {
+	case *u.p == nil:
+		return ""
+	case *u.p == u.last:
+		return redactURL(u.raw)
+	}
+	return redactURL((*u.p).String())
+}
+
+// URLVar defines a URL flag with specified name, default value, and usage string.
+// The argument p points to an *http.URL variable in which to store the value of
+// the flag.  The URLs accepted are restricted by opts.  When shown, as in help
+// output, the password of a URL is replaced by "xxxxx".  An empty default value
+// leaves the variable nil.
+func URLVar(p **http.URL, name, shortName string, value string, opts URLOptions, usage string) {
+	var def *http.URL
+	if value != "" {
+		var ok bool
+		if def, ok = opts.parse(value); !ok {
+			fmt.Fprintln(os.Stderr, "flag default invalid:", name)
+			panic("flag default invalid")
+		}
+	}
+	add(name, shortName, newURLValue(def, opts.complete(value), opts, p), usage)
+}
+
+// URL defines a URL flag with specified name, default value, and usage string.
+// The return value is the address of an *http.URL variable that stores the value
+// of the flag.
+func URL(name, shortName string, value string, opts URLOptions, usage string) **http.URL {
+	p := new(*http.URL)
+	URLVar(p, name, shortName, value, opts, usage)
+	return p
+}